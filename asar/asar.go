@@ -1,18 +1,27 @@
 package asar
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
 	"path"
+
+	"github.com/kaey/gamearc/internal/arc"
 )
 
 type Archive struct {
+	*arc.FS
+
 	r    io.ReaderAt
 	size int64
 
+	// Files are in the order they are listed in the header.
 	Files []File
+
+	// Unpacked lists paths of files stored in app.asar.unpacked directory outside of the archive.
+	Unpacked []string
 }
 
 type File struct {
@@ -20,21 +29,39 @@ type File struct {
 	path   string
 	offset int64
 	size   int64
+	exec   bool
 }
 
 func (f *File) Path() string {
 	return f.path
 }
 
+func (f *File) Offset() int64 {
+	return f.offset
+}
+
+func (f *File) Size() int64 {
+	return f.size
+}
+
 func (f *File) Reader() *io.SectionReader {
 	return io.NewSectionReader(f.r, f.offset, f.size)
 }
 
+func (f *File) Open() (io.ReadSeeker, error) {
+	return f.Reader(), nil
+}
+
+func (f *File) Meta() map[string]any {
+	return map[string]any{"executable": f.exec}
+}
+
 func OpenArchive(r io.ReaderAt, size int64) (*Archive, error) {
 	a := &Archive{r: r, size: size}
 	if err := a.readIndex(); err != nil {
 		return nil, fmt.Errorf("read index: %w", err)
 	}
+	a.FS = arc.NewFS(arc.Entries(a.Files))
 
 	return a, nil
 }
@@ -65,7 +92,8 @@ func (a *Archive) readIndex() error {
 }
 
 func (a *Archive) recurse(v file, curpath string, dataOffset int64) error {
-	for name, f := range v.Files {
+	for _, e := range v.Files {
+		name, f := e.name, e.file
 		if name == ".." || name == "/" {
 			// Use manual concatenation here because path.Join cleans paths.
 			return fmt.Errorf("bad path: %s", curpath+"/"+name)
@@ -77,11 +105,17 @@ func (a *Archive) recurse(v file, curpath string, dataOffset int64) error {
 			continue
 		}
 
+		if f.Unpacked {
+			a.Unpacked = append(a.Unpacked, path.Join(curpath, name))
+			continue
+		}
+
 		a.Files = append(a.Files, File{
 			r:      a.r,
 			path:   path.Join(curpath, name),
 			offset: f.Offset + dataOffset,
 			size:   f.Size,
+			exec:   f.Exec,
 		})
 	}
 
@@ -89,10 +123,50 @@ func (a *Archive) recurse(v file, curpath string, dataOffset int64) error {
 }
 
 type file struct {
-	Files  map[string]file `json:"files"`
-	Offset int64           `json:"offset,string"`
-	Size   int64           `json:"size"`
-	Exec   bool            `json:"executable"`
+	Files  dir   `json:"files"`
+	Offset int64 `json:"offset,string"`
+	Size   int64 `json:"size"`
+	Exec   bool  `json:"executable"`
+
+	Unpacked bool `json:"unpacked"`
+}
+
+// dir is decoded from json object of files in header order, which is the order files are listed in.
+type dir []dirEntry
+
+type dirEntry struct {
+	name string
+	file file
+}
+
+func (d *dir) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if t, err := dec.Token(); err != nil {
+		return err
+	} else if t != json.Delim('{') {
+		return fmt.Errorf("expected files object, got %v", t)
+	}
+
+	// Empty directory is not nil, nil files mean that entry is a file.
+	*d = dir{}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var e dirEntry
+		e.name = t.(string)
+		if err := dec.Decode(&e.file); err != nil {
+			return err
+		}
+		*d = append(*d, e)
+	}
+	_, err := dec.Token()
+
+	return err
 }
 
 var le = binary.LittleEndian
@@ -0,0 +1,240 @@
+package asar
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+const integrityBlockSize = 4 << 20
+
+// FileHeader describes a file added to Writer.
+type FileHeader struct {
+	Name       string
+	Size       int64
+	Executable bool
+
+	// Unpacked files are only listed in the index,
+	// their data is expected to be in app.asar.unpacked directory next to the archive.
+	Unpacked bool
+}
+
+// Writer creates asar archives.
+// Files are collected with Add, AddFile or AddFS and written out by Close,
+// because index with file offsets precedes file data.
+type Writer struct {
+	w         io.Writer
+	integrity bool
+	files     []writerFile
+}
+
+type writerFile struct {
+	FileHeader
+	open func() (io.ReadCloser, error)
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// SetIntegrity enables sha256 integrity blocks in the index.
+// Each file is read twice when enabled, once for hashing and once for writing.
+func (w *Writer) SetIntegrity(v bool) {
+	w.integrity = v
+}
+
+// Add adds a regular file. Open is called when the archive is written and must return exactly size bytes.
+func (w *Writer) Add(name string, size int64, open func() (io.ReadCloser, error)) error {
+	return w.AddFile(FileHeader{Name: name, Size: size}, open)
+}
+
+// AddFile is like Add, but allows to set executable and unpacked flags.
+// Open is not called for unpacked files unless integrity is enabled.
+func (w *Writer) AddFile(fh FileHeader, open func() (io.ReadCloser, error)) error {
+	if !fs.ValidPath(fh.Name) || fh.Name == "." {
+		return fmt.Errorf("bad path: %q", fh.Name)
+	}
+	if fh.Size < 0 {
+		return fmt.Errorf("%s: bad size %d", fh.Name, fh.Size)
+	}
+
+	w.files = append(w.files, writerFile{FileHeader: fh, open: open})
+	return nil
+}
+
+// AddFS adds all regular files from fsys, files with any executable bit set are marked executable.
+func (w *Writer) AddFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		fh := FileHeader{Name: name, Size: info.Size(), Executable: info.Mode()&0o111 != 0}
+		return w.AddFile(fh, func() (io.ReadCloser, error) {
+			return fsys.Open(name)
+		})
+	})
+}
+
+type dirNode struct {
+	Files map[string]any `json:"files"`
+}
+
+type fileNode struct {
+	Size       int64      `json:"size"`
+	Offset     string     `json:"offset,omitempty"`
+	Unpacked   bool       `json:"unpacked,omitempty"`
+	Executable bool       `json:"executable,omitempty"`
+	Integrity  *integrity `json:"integrity,omitempty"`
+}
+
+type integrity struct {
+	Algorithm string   `json:"algorithm"`
+	Hash      string   `json:"hash"`
+	BlockSize int      `json:"blockSize"`
+	Blocks    []string `json:"blocks"`
+}
+
+// Close writes the archive. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	root := &dirNode{Files: make(map[string]any)}
+	var offset int64
+	for _, f := range w.files {
+		node := &fileNode{Size: f.Size, Unpacked: f.Unpacked, Executable: f.Executable}
+		if !f.Unpacked {
+			node.Offset = strconv.FormatInt(offset, 10)
+			offset += f.Size
+		}
+		if w.integrity {
+			in, err := hashFile(f)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.Name, err)
+			}
+			node.Integrity = in
+		}
+
+		if err := root.add(f.Name, node); err != nil {
+			return err
+		}
+	}
+
+	index, err := json.Marshal(root)
+	if err != nil {
+		return err
+	}
+
+	// Index is a pickle of a pickle, each one is prefixed with its payload size
+	// and payload is padded to 4 bytes.
+	pad := (4 - len(index)%4) % 4
+	var header [16]byte
+	le.PutUint32(header[0:4], 4)
+	le.PutUint32(header[4:8], uint32(8+len(index)+pad))
+	le.PutUint32(header[8:12], uint32(4+len(index)+pad))
+	le.PutUint32(header[12:16], uint32(len(index)))
+
+	bw := bufio.NewWriter(w.w)
+	bw.Write(header[:])
+	bw.Write(index)
+	bw.Write(make([]byte, pad))
+
+	for _, f := range w.files {
+		if f.Unpacked {
+			continue
+		}
+		if err := writeFile(bw, f); err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+
+	return bw.Flush()
+}
+
+func (d *dirNode) add(name string, node *fileNode) error {
+	elems := strings.Split(name, "/")
+	for _, e := range elems[:len(elems)-1] {
+		switch n := d.Files[e].(type) {
+		case nil:
+			sub := &dirNode{Files: make(map[string]any)}
+			d.Files[e] = sub
+			d = sub
+		case *dirNode:
+			d = n
+		default:
+			return fmt.Errorf("%s: parent is a file", name)
+		}
+	}
+
+	base := elems[len(elems)-1]
+	if _, ok := d.Files[base]; ok {
+		return fmt.Errorf("%s: duplicate path", name)
+	}
+	d.Files[base] = node
+
+	return nil
+}
+
+func hashFile(f writerFile) (*integrity, error) {
+	r, err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	in := &integrity{Algorithm: "SHA256", BlockSize: integrityBlockSize, Blocks: []string{}}
+	h := sha256.New()
+	buf := make([]byte, integrityBlockSize)
+	var total int64
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		block := sha256.Sum256(buf[:n])
+		in.Blocks = append(in.Blocks, hex.EncodeToString(block[:]))
+		h.Write(buf[:n])
+		total += int64(n)
+
+		// Like asar, the last block is always added, it is empty if size is a multiple of block size.
+		if err != nil {
+			break
+		}
+	}
+	if total != f.Size {
+		return nil, fmt.Errorf("expected %d bytes, got %d", f.Size, total)
+	}
+	in.Hash = hex.EncodeToString(h.Sum(nil))
+
+	return in, nil
+}
+
+func writeFile(w io.Writer, f writerFile) error {
+	r, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	n, err := io.Copy(w, io.LimitReader(r, f.Size))
+	if err != nil {
+		return err
+	}
+	if n != f.Size {
+		return fmt.Errorf("expected %d bytes, got %d", f.Size, n)
+	}
+
+	return nil
+}
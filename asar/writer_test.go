@@ -0,0 +1,117 @@
+package asar
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json":        {Data: []byte(`{"main":"main.js"}`)},
+		"main.js":             {Data: []byte("console.log(1)\n")},
+		"lib/a/b/c.js":        {Data: []byte(strings.Repeat("c", 1000))},
+		"lib/empty.js":        {Data: nil},
+		"bin/run":             {Data: []byte("#!/bin/sh\n"), Mode: 0o755},
+		"node_modules/x/y.js": {Data: []byte("y")},
+	}
+
+	for _, integrity := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		w.SetIntegrity(integrity)
+		if err := w.AddFS(fsys); err != nil {
+			t.Fatal(err)
+		}
+		err := w.AddFile(FileHeader{Name: "native/addon.node", Size: 3, Unpacked: true}, func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("elf")), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		a, err := OpenArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(a.Files) != len(fsys) {
+			t.Fatalf("got %d files, want %d", len(a.Files), len(fsys))
+		}
+		for _, f := range a.Files {
+			want, ok := fsys[f.Path()]
+			if !ok {
+				t.Errorf("unexpected path %q", f.Path())
+				continue
+			}
+			data, err := io.ReadAll(f.Reader())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, want.Data) {
+				t.Errorf("%s: got %q, want %q", f.Path(), data, want.Data)
+			}
+			if exec := want.Mode&0o111 != 0; f.Meta()["executable"] != exec {
+				t.Errorf("%s: got executable %v, want %v", f.Path(), f.Meta()["executable"], exec)
+			}
+		}
+		if len(a.Unpacked) != 1 || a.Unpacked[0] != "native/addon.node" {
+			t.Errorf("got unpacked %q", a.Unpacked)
+		}
+	}
+}
+
+func TestWriterConflict(t *testing.T) {
+	open := func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("")), nil }
+	for _, names := range [][]string{{"a", "a"}, {"a", "a/b"}} {
+		w := NewWriter(io.Discard)
+		for _, name := range names {
+			if err := w.Add(name, 0, open); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err == nil {
+			t.Errorf("%q: expected error", names)
+		}
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	empty := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	for _, tc := range []struct {
+		size   int64
+		blocks int
+	}{
+		{0, 1},
+		{1, 1},
+		{integrityBlockSize - 1, 1},
+		// Last block is empty, the same as asar does.
+		{integrityBlockSize, 2},
+		{integrityBlockSize + 1, 2},
+	} {
+		f := writerFile{FileHeader: FileHeader{Size: tc.size}, open: func() (io.ReadCloser, error) {
+			return io.NopCloser(io.LimitReader(zeros{}, tc.size)), nil
+		}}
+		in, err := hashFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(in.Blocks) != tc.blocks {
+			t.Errorf("size %d: got %d blocks, want %d", tc.size, len(in.Blocks), tc.blocks)
+		}
+		if tc.size%integrityBlockSize == 0 && in.Blocks[len(in.Blocks)-1] != empty {
+			t.Errorf("size %d: got last block %s, want hash of empty block", tc.size, in.Blocks[len(in.Blocks)-1])
+		}
+	}
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
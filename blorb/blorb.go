@@ -5,9 +5,15 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"slices"
+	"strings"
+
+	"github.com/kaey/gamearc/internal/arc"
 )
 
 type Archive struct {
+	*arc.FS
+
 	r    io.ReaderAt
 	size int64
 
@@ -20,12 +26,17 @@ type Archive struct {
 
 type File struct {
 	r      io.ReaderAt
+	usage  string
 	id     int
 	format string
 	offset int64
 	size   int64
 }
 
+func (f *File) Usage() string {
+	return f.usage
+}
+
 func (f *File) ID() int {
 	return f.id
 }
@@ -34,15 +45,37 @@ func (f *File) Format() string {
 	return f.format
 }
 
+// Path returns a synthesized path in form usage/id.format, for example pict/0001.png.
+func (f *File) Path() string {
+	return fmt.Sprintf("%s/%04d.%s", f.usage, f.id, f.format)
+}
+
+func (f *File) Offset() int64 {
+	return f.offset
+}
+
+func (f *File) Size() int64 {
+	return f.size
+}
+
 func (f *File) Reader() *io.SectionReader {
 	return io.NewSectionReader(f.r, f.offset, f.size)
 }
 
+func (f *File) Open() (io.ReadSeeker, error) {
+	return f.Reader(), nil
+}
+
+func (f *File) Meta() map[string]any {
+	return map[string]any{"usage": f.usage, "id": f.id, "format": f.format}
+}
+
 func OpenArchive(r io.ReaderAt, size int64) (*Archive, error) {
 	a := &Archive{r: r, size: size}
 	if err := a.readIndex(); err != nil {
 		return nil, fmt.Errorf("read index: %w", err)
 	}
+	a.FS = arc.NewFS(slices.Concat(arc.Entries(a.Pics), arc.Entries(a.Snds), arc.Entries(a.Datas), arc.Entries(a.Execs), arc.Entries(a.Gluls)))
 
 	return a, nil
 }
@@ -106,6 +139,7 @@ func (a *Archive) readIndex() error {
 
 		file := File{
 			r:      a.r,
+			usage:  usage(typ),
 			id:     id,
 			format: format,
 			offset: offset + 8,
@@ -131,4 +165,9 @@ func (a *Archive) readIndex() error {
 	return nil
 }
 
+// usage converts resource type into a lowercase name suitable for use in paths.
+func usage(typ []byte) string {
+	return strings.ToLower(strings.TrimSpace(string(typ)))
+}
+
 var be = binary.BigEndian
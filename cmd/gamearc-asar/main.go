@@ -6,15 +6,18 @@ import (
 	"io"
 	"log"
 	"os"
-	"path/filepath"
 
 	"github.com/kaey/gamearc/asar"
+	"github.com/kaey/gamearc/internal/cli"
 	"github.com/kaey/gamearc/internal/flagx"
 )
 
 func main() {
+	cf := cli.RegisterFlags()
+	packFlag := flag.Bool("pack", false, "Pack SRCDIR into archive DSTFILE instead of extracting")
+	integrityFlag := flag.Bool("integrity", false, "Add integrity hashes to the index when using -pack")
 	versionFlag := flag.Bool("version", false, "Print version and exit")
-	flag.Usage = flagx.Usage("gamearc-asar [FLAGS] SRCFILE DSTDIR")
+	flag.Usage = flagx.Usage("gamearc-asar [FLAGS] SRCFILE DSTDIR\n  gamearc-asar -pack [FLAGS] SRCDIR DSTFILE")
 	flag.Parse()
 
 	if *versionFlag {
@@ -22,57 +25,53 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *packFlag {
+		if flag.Arg(0) == "" || flag.Arg(1) == "" {
+			flagx.Fail("Specify SRCDIR and DSTFILE")
+		}
+
+		if err := Pack(flag.Arg(0), flag.Arg(1), *integrityFlag); err != nil {
+			log.Fatalln(err)
+		}
+
+		return
+	}
+
 	srcfile := flag.Arg(0)
 	if srcfile == "" {
 		flagx.Fail("Specify SRCFILE and DSTDIR")
 	}
 
 	dstdir := flag.Arg(1)
-	if dstdir == "" {
+	if dstdir == "" && !cf.List {
 		flagx.Fail("Specify DSTDIR")
 	}
 
-	if err := Main(srcfile, dstdir); err != nil {
+	if err := Main(srcfile, dstdir, cf); err != nil {
 		log.Fatalln(err)
 	}
 }
 
-func Main(srcfile, dstdir string) error {
-	r, err := os.Open(srcfile)
-	if err != nil {
-		return err
-	}
-
-	ri, err := r.Stat()
+func Main(srcfile, dstdir string, cf *cli.Flags) error {
+	r, size, err := cli.Open(srcfile)
 	if err != nil {
 		return err
 	}
+	defer r.Close()
 
-	arc, err := asar.OpenArchive(r, ri.Size())
+	arc, err := asar.OpenArchive(r, size)
 	if err != nil {
 		return err
 	}
 
-	for _, f := range arc.Files {
-		dstfile := filepath.Join(dstdir, filepath.FromSlash(f.Path()))
-		if err := os.MkdirAll(filepath.Dir(dstfile), 0755); err != nil {
-			return err
-		}
-
-		r := f.Reader()
-		w, err := os.Create(dstfile)
-		if err != nil {
-			return err
-		}
-
-		if _, err := io.Copy(w, r); err != nil {
-			return err
-		}
+	return cf.Run(arc.Entries(), dstdir)
+}
 
-		if err := w.Close(); err != nil {
-			return err
-		}
-	}
+func Pack(srcdir, dstfile string, integrity bool) error {
+	return cli.Pack(srcdir, dstfile, func(w io.Writer) (cli.Packer, error) {
+		aw := asar.NewWriter(w)
+		aw.SetIntegrity(integrity)
 
-	return nil
+		return aw, nil
+	})
 }
@@ -3,18 +3,19 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"path/filepath"
 
 	"github.com/kaey/gamearc/blorb"
+	"github.com/kaey/gamearc/internal/cli"
 	"github.com/kaey/gamearc/internal/flagx"
 )
 
 func main() {
+	cf := cli.RegisterFlags()
 	versionFlag := flag.Bool("version", false, "Print version and exit")
-	flag.Usage = flagx.Usage("gamearc-blorb [FLAGS] SRCFILE DSTDIR")
+	flag.Usage = flagx.Usage("gamearc-blorb [FLAGS] SRCFILE DSTDIR\n  Pictures are written as DSTDIR/NNNN.ext, filters match their archive path pict/NNNN.ext")
 	flag.Parse()
 
 	if *versionFlag {
@@ -28,27 +29,32 @@ func main() {
 	}
 
 	dstdir := flag.Arg(1)
-	if dstdir == "" {
+	if dstdir == "" && !cf.List {
 		flagx.Fail("Specify DSTDIR")
 	}
 
-	if err := Main(srcfile, dstdir); err != nil {
+	if err := Main(srcfile, dstdir, cf); err != nil {
 		log.Fatalln(err)
 	}
 }
 
-func Main(srcfile, dstdir string) error {
-	r, err := os.Open(srcfile)
+func Main(srcfile, dstdir string, cf *cli.Flags) error {
+	r, size, err := cli.Open(srcfile)
 	if err != nil {
 		return err
 	}
+	defer r.Close()
 
-	ri, err := r.Stat()
+	arc, err := blorb.OpenArchive(r, size)
 	if err != nil {
 		return err
 	}
 
-	arc, err := blorb.OpenArchive(r, ri.Size())
+	if cf.List {
+		return cf.Run(arc.Entries(), dstdir)
+	}
+
+	entries, err := cf.Filter(arc.Entries())
 	if err != nil {
 		return err
 	}
@@ -57,20 +63,15 @@ func Main(srcfile, dstdir string) error {
 		return err
 	}
 
-	for _, f := range arc.Pics {
-		name := fmt.Sprintf("%04d.%s", f.ID(), f.Format())
-
-		r := f.Reader()
-		w, err := os.Create(filepath.Join(dstdir, name))
-		if err != nil {
-			return err
+	for _, e := range entries {
+		f := e.(*blorb.File)
+		if f.Usage() != "pict" {
+			continue
 		}
 
-		if _, err := io.Copy(w, r); err != nil {
-			return err
-		}
-
-		if err := w.Close(); err != nil {
+		// Usage directory of the path is dropped, since only pictures are written.
+		name := fmt.Sprintf("%04d.%s", f.ID(), f.Format())
+		if err := cli.WriteFile(f, filepath.Join(dstdir, name)); err != nil {
 			return err
 		}
 	}
@@ -6,15 +6,19 @@ import (
 	"io"
 	"log"
 	"os"
-	"path/filepath"
+	"strconv"
 
+	"github.com/kaey/gamearc/internal/cli"
 	"github.com/kaey/gamearc/internal/flagx"
 	"github.com/kaey/gamearc/rgssad"
 )
 
 func main() {
+	cf := cli.RegisterFlags()
+	packFlag := flag.Bool("pack", false, "Pack SRCDIR into archive DSTFILE instead of extracting")
+	keyFlag := flag.String("key", "", "Header key for -pack in hex, random if empty")
 	versionFlag := flag.Bool("version", false, "Print version and exit")
-	flag.Usage = flagx.Usage("gamearc-rgssad [FLAGS] SRCFILE DSTDIR")
+	flag.Usage = flagx.Usage("gamearc-rgssad [FLAGS] SRCFILE DSTDIR\n  gamearc-rgssad -pack [FLAGS] SRCDIR DSTFILE")
 	flag.Parse()
 
 	if *versionFlag {
@@ -22,57 +26,64 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *packFlag {
+		if flag.Arg(0) == "" || flag.Arg(1) == "" {
+			flagx.Fail("Specify SRCDIR and DSTFILE")
+		}
+
+		if err := Pack(flag.Arg(0), flag.Arg(1), *keyFlag); err != nil {
+			log.Fatalln(err)
+		}
+
+		return
+	}
+
 	srcfile := flag.Arg(0)
 	if srcfile == "" {
 		flagx.Fail("Specify SRCFILE and DSTDIR")
 	}
 
 	dstdir := flag.Arg(1)
-	if dstdir == "" {
+	if dstdir == "" && !cf.List {
 		flagx.Fail("Specify DSTDIR")
 	}
 
-	if err := Main(srcfile, dstdir); err != nil {
+	if err := Main(srcfile, dstdir, cf); err != nil {
 		log.Fatalln(err)
 	}
 }
 
-func Main(srcfile, dstdir string) error {
-	r, err := os.Open(srcfile)
-	if err != nil {
-		return err
-	}
-
-	ri, err := r.Stat()
+func Main(srcfile, dstdir string, cf *cli.Flags) error {
+	r, size, err := cli.Open(srcfile)
 	if err != nil {
 		return err
 	}
+	defer r.Close()
 
-	arc, err := rgssad.OpenArchive(r, ri.Size())
+	arc, err := rgssad.OpenArchive(r, size)
 	if err != nil {
 		return err
 	}
 
-	for _, f := range arc.Files {
-		dstfile := filepath.Join(dstdir, filepath.FromSlash(f.Path()))
-		if err := os.MkdirAll(filepath.Dir(dstfile), 0755); err != nil {
-			return err
-		}
+	return cf.Run(arc.Entries(), dstdir)
+}
 
-		r := f.Reader()
-		w, err := os.Create(dstfile)
+func Pack(srcdir, dstfile, key string) error {
+	var seed uint64
+	if key != "" {
+		var err error
+		seed, err = strconv.ParseUint(key, 16, 32)
 		if err != nil {
-			return err
-		}
-
-		if _, err := io.Copy(w, r); err != nil {
-			return err
+			return fmt.Errorf("malformed key: %w", err)
 		}
+	}
 
-		if err := w.Close(); err != nil {
-			return err
+	return cli.Pack(srcdir, dstfile, func(w io.Writer) (cli.Packer, error) {
+		aw := rgssad.NewWriter(w)
+		if key != "" {
+			aw.SetKey(uint32(seed))
 		}
-	}
 
-	return nil
+		return aw, nil
+	})
 }
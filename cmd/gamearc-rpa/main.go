@@ -1,20 +1,31 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/kaey/gamearc/internal/cli"
 	"github.com/kaey/gamearc/internal/flagx"
+	"github.com/kaey/gamearc/renpy/tl"
 	"github.com/kaey/gamearc/rpa"
 )
 
 func main() {
+	cf := cli.RegisterFlags()
+	packFlag := flag.Bool("pack", false, "Pack SRCDIR into archive DSTFILE instead of extracting")
+	keyFlag := flag.String("key", "", "Key for -pack in hex, random if empty")
+	tlFlag := flag.String("tl", "", "Write Ren'Py translation files for language `LANG` into DSTDIR instead of extracting")
 	versionFlag := flag.Bool("version", false, "Print version and exit")
-	flag.Usage = flagx.Usage("gamearc-rpa [FLAGS] SRCFILE DSTDIR")
+	flag.Usage = flagx.Usage("gamearc-rpa [FLAGS] SRCFILE DSTDIR\n  gamearc-rpa -pack [FLAGS] SRCDIR DSTFILE\n  gamearc-rpa -tl LANG SRCFILE DSTDIR")
 	flag.Parse()
 
 	if *versionFlag {
@@ -22,57 +33,132 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *packFlag {
+		if flag.Arg(0) == "" || flag.Arg(1) == "" {
+			flagx.Fail("Specify SRCDIR and DSTFILE")
+		}
+
+		if err := Pack(flag.Arg(0), flag.Arg(1), *keyFlag); err != nil {
+			log.Fatalln(err)
+		}
+
+		return
+	}
+
 	srcfile := flag.Arg(0)
 	if srcfile == "" {
 		flagx.Fail("Specify SRCFILE and DSTDIR")
 	}
 
 	dstdir := flag.Arg(1)
-	if dstdir == "" {
+	if dstdir == "" && !cf.List {
 		flagx.Fail("Specify DSTDIR")
 	}
 
-	if err := Main(srcfile, dstdir); err != nil {
+	if *tlFlag != "" {
+		if dstdir == "" {
+			flagx.Fail("Specify DSTDIR")
+		}
+		if err := Translate(srcfile, dstdir, *tlFlag); err != nil {
+			log.Fatalln(err)
+		}
+
+		return
+	}
+
+	if err := Main(srcfile, dstdir, cf); err != nil {
 		log.Fatalln(err)
 	}
 }
 
-func Main(srcfile, dstdir string) error {
-	r, err := os.Open(srcfile)
+func Main(srcfile, dstdir string, cf *cli.Flags) error {
+	r, size, err := cli.Open(srcfile)
 	if err != nil {
 		return err
 	}
+	defer r.Close()
 
-	ri, err := r.Stat()
+	arc, err := openArchive(srcfile, r, size)
 	if err != nil {
 		return err
 	}
 
-	arc, err := rpa.OpenArchive(r, ri.Size())
+	return cf.Run(arc.Entries(), dstdir)
+}
+
+// Translate writes translation skeleton of scripts in srcfile to DSTDIR/tl/LANG,
+// and the same dialogue and strings to DSTDIR/LANG.csv and DSTDIR/LANG.po.
+func Translate(srcfile, dstdir, lang string) error {
+	r, size, err := cli.Open(srcfile)
 	if err != nil {
 		return err
 	}
+	defer r.Close()
 
-	for _, f := range arc.Files {
-		dstfile := filepath.Join(dstdir, filepath.FromSlash(f.Path()))
-		if err := os.MkdirAll(filepath.Dir(dstfile), 0755); err != nil {
-			return err
-		}
+	arc, err := openArchive(srcfile, r, size)
+	if err != nil {
+		return err
+	}
 
-		r := f.Reader()
-		w, err := os.Create(dstfile)
-		if err != nil {
+	t, err := tl.Extract(arc)
+	if err != nil {
+		return err
+	}
+
+	for name, data := range t.TL(lang) {
+		dstfile := filepath.Join(dstdir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dstfile), 0o755); err != nil {
 			return err
 		}
-
-		if _, err := io.Copy(w, r); err != nil {
+		if err := os.WriteFile(dstfile, data, 0o644); err != nil {
 			return err
 		}
+	}
 
-		if err := w.Close(); err != nil {
-			return err
+	var csv, po bytes.Buffer
+	if err := t.WriteCSV(&csv); err != nil {
+		return err
+	}
+	if err := t.WritePO(&po, lang); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dstdir, lang+".csv"), csv.Bytes(), 0o644); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dstdir, lang+".po"), po.Bytes(), 0o644)
+}
+
+// openArchive opens RPA-1.0 archive if there is an .rpi index next to srcfile and any other version otherwise.
+func openArchive(srcfile string, r io.ReaderAt, size int64) (*rpa.Archive, error) {
+	index, err := os.Open(strings.TrimSuffix(srcfile, filepath.Ext(srcfile)) + ".rpi")
+	if errors.Is(err, fs.ErrNotExist) {
+		return rpa.OpenArchive(r, size)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer index.Close()
+
+	return rpa.OpenArchiveWithIndex(r, size, index)
+}
+
+func Pack(srcdir, dstfile, key string) error {
+	var k uint64
+	if key != "" {
+		var err error
+		k, err = strconv.ParseUint(key, 16, 32)
+		if err != nil {
+			return fmt.Errorf("malformed key: %w", err)
 		}
 	}
 
-	return nil
+	return cli.Pack(srcdir, dstfile, func(w io.Writer) (cli.Packer, error) {
+		aw := rpa.NewWriter(w)
+		if key != "" {
+			aw.SetKey(uint32(k))
+		}
+
+		return aw, nil
+	})
 }
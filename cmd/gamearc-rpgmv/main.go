@@ -11,10 +11,12 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/kaey/gamearc/internal/cli"
 	"github.com/kaey/gamearc/internal/flagx"
 )
 
 func main() {
+	ff := cli.RegisterMatchFlags()
 	keyFilePath := flag.String("key-file", "", "Path to system.json")
 	versionFlag := flag.Bool("version", false, "Print version and exit")
 	flag.Usage = flagx.Usage("gamearc-rpgmv [FLAGS] SRCDIR DSTDIR")
@@ -39,12 +41,12 @@ func main() {
 		flagx.Fail("Specify DSTDIR")
 	}
 
-	if err := Main(srcfile, dstdir, *keyFilePath); err != nil {
+	if err := Main(srcfile, dstdir, *keyFilePath, ff); err != nil {
 		log.Fatalln(err)
 	}
 }
 
-func Main(srcdir, dstdir, keyFilePath string) error {
+func Main(srcdir, dstdir, keyFilePath string, ff *cli.FilterFlags) error {
 	keyFileData, err := os.ReadFile(keyFilePath)
 	if err != nil {
 		return fmt.Errorf("key-file read error: %w", err)
@@ -80,6 +82,14 @@ filesLoop:
 			continue filesLoop
 		}
 
+		match, err := ff.Match(srcfile.Name())
+		if err != nil {
+			return err
+		}
+		if !match {
+			continue filesLoop
+		}
+
 		ext := filepath.Ext(srcfile.Name())                            // extension with dot (for ex .rpgmvp)
 		base := strings.TrimSuffix(filepath.Base(srcfile.Name()), ext) // basename without extension (for ex w04_16)
 
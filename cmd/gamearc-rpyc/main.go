@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kaey/gamearc/internal/cli"
+	"github.com/kaey/gamearc/internal/flagx"
+	"github.com/kaey/gamearc/renpy/rpyc"
+)
+
+func main() {
+	ff := cli.RegisterMatchFlags()
+	versionFlag := flag.Bool("version", false, "Print version and exit")
+	flag.Usage = flagx.Usage("gamearc-rpyc [FLAGS] SRC DSTDIR\n  SRC is a .rpyc file or a directory, which is searched for .rpyc files recursively")
+	flag.Parse()
+
+	if *versionFlag {
+		fmt.Fprintf(os.Stderr, "%s", flagx.Version())
+		os.Exit(0)
+	}
+
+	src := flag.Arg(0)
+	if src == "" {
+		flagx.Fail("Specify SRC and DSTDIR")
+	}
+
+	dstdir := flag.Arg(1)
+	if dstdir == "" {
+		flagx.Fail("Specify DSTDIR")
+	}
+
+	if err := Main(src, dstdir, ff); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// Main decompiles src into dstdir, keeping paths relative to src.
+// Filters are matched against those relative paths, or the file name if src is a file.
+// Files which fail to decompile are logged and skipped.
+func Main(src, dstdir string, ff *cli.FilterFlags) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		name := filepath.Base(src)
+		if ok, err := ff.Match(name); err != nil || !ok {
+			return err
+		}
+
+		return decompile(src, filepath.Join(dstdir, rpyName(name)))
+	}
+
+	failed := 0
+	err = filepath.WalkDir(src, func(srcfile string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(srcfile) != ".rpyc" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, srcfile)
+		if err != nil {
+			return err
+		}
+		if ok, err := ff.Match(filepath.ToSlash(rel)); err != nil || !ok {
+			return err
+		}
+		if err := decompile(srcfile, filepath.Join(dstdir, rpyName(rel))); err != nil {
+			log.Printf("%s: %v", srcfile, err)
+			failed++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d files failed to decompile", failed)
+	}
+
+	return nil
+}
+
+func decompile(srcfile, dstfile string) error {
+	data, err := os.ReadFile(srcfile)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := rpyc.Decompile(&buf, data); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dstfile), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(dstfile, buf.Bytes(), 0o644)
+}
+
+func rpyName(name string) string {
+	return strings.TrimSuffix(name, ".rpyc") + ".rpy"
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/kaey/gamearc/internal/cli"
+	"github.com/kaey/gamearc/internal/flagx"
+	"github.com/kaey/gamearc/renpy/save"
+)
+
+func main() {
+	versionFlag := flag.Bool("version", false, "Print version and exit")
+	flag.Usage = flagx.Usage("gamearc-rpysave [FLAGS] SAVEFILE [DSTDIR]\n  Prints save metadata, log as json and screenshot are written into DSTDIR if it is specified")
+	flag.Parse()
+
+	if *versionFlag {
+		fmt.Fprintf(os.Stderr, "%s", flagx.Version())
+		os.Exit(0)
+	}
+
+	srcfile := flag.Arg(0)
+	if srcfile == "" {
+		flagx.Fail("Specify SAVEFILE")
+	}
+
+	if err := Main(srcfile, flag.Arg(1)); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func Main(srcfile, dstdir string) error {
+	r, size, err := cli.Open(srcfile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	s, err := save.OpenSave(r, size)
+	if err != nil {
+		return err
+	}
+
+	if err := printMeta(os.Stdout, s); err != nil {
+		return err
+	}
+	if dstdir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(dstdir, 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Join(dstdir, "log.json"))
+	if err != nil {
+		return err
+	}
+	if err := s.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	png, err := s.Screenshot()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dstdir, "screenshot.png"), png, 0o644)
+}
+
+// printMeta prints save name, Ren'Py version and all metadata fields sorted by name.
+func printMeta(w io.Writer, s *save.Save) error {
+	fmt.Fprintf(w, "name: %s\n", s.Name())
+	fmt.Fprintf(w, "renpy_version: %s\n", s.RenpyVersion())
+
+	keys := make([]string, 0, len(s.Meta))
+	for k := range s.Meta {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		v, err := json.Marshal(s.Meta[k])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s: %s\n", k, v)
+	}
+
+	return nil
+}
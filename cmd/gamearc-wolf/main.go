@@ -1,19 +1,27 @@
 package main
 
 import (
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"path/filepath"
 
+	"github.com/kaey/gamearc/internal/cli"
 	"github.com/kaey/gamearc/internal/flagx"
 	"github.com/kaey/gamearc/wolf"
 )
 
 func main() {
+	cf := cli.RegisterFlags()
+	keyFlag := flag.String("key", "", "Archive key, either 24 hex digits of a raw key or a password")
+	exeFlag := flag.String("exe", "", "Find archive key in Game.exe, ignored if -key is set")
+	packFlag := flag.Bool("pack", false, "Pack SRCDIR into archive DSTFILE instead of extracting")
+	compressFlag := flag.Bool("compress", false, "Compress files with -pack")
+	v8Flag := flag.Bool("v8", false, "Create version 8 archive with -pack instead of version 6, -key must be a password")
 	versionFlag := flag.Bool("version", false, "Print version and exit")
-	flag.Usage = flagx.Usage("gamearc-wolf [FLAGS] SRCFILE DSTDIR")
+	flag.Usage = flagx.Usage("gamearc-wolf [FLAGS] SRCFILE DSTDIR\n  gamearc-wolf -pack [FLAGS] SRCDIR DSTFILE")
 	flag.Parse()
 
 	if *versionFlag {
@@ -21,52 +29,100 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *packFlag {
+		if flag.Arg(0) == "" || flag.Arg(1) == "" {
+			flagx.Fail("Specify SRCDIR and DSTFILE")
+		}
+
+		if err := Pack(flag.Arg(0), flag.Arg(1), *keyFlag, *compressFlag, *v8Flag); err != nil {
+			log.Fatalln(err)
+		}
+
+		return
+	}
+
 	srcfile := flag.Arg(0)
 	if srcfile == "" {
 		flagx.Fail("Specify SRCFILE and DSTDIR")
 	}
 
 	dstdir := flag.Arg(1)
-	if dstdir == "" {
+	if dstdir == "" && !cf.List {
 		flagx.Fail("Specify DSTDIR")
 	}
 
-	if err := Main(srcfile, dstdir); err != nil {
+	if err := Main(srcfile, dstdir, *keyFlag, *exeFlag, cf); err != nil {
 		log.Fatalln(err)
 	}
 }
 
-func Main(srcfile, dstdir string) error {
-	r, err := os.Open(srcfile)
+func Main(srcfile, dstdir, key, exefile string, cf *cli.Flags) error {
+	r, size, err := cli.Open(srcfile)
 	if err != nil {
 		return err
 	}
+	defer r.Close()
 
-	ri, err := r.Stat()
-	if err != nil {
-		return err
+	opts := parseKey(key)
+	if key == "" && exefile != "" {
+		opts, err = findKey(exefile, r, size)
+		if err != nil {
+			return err
+		}
 	}
 
-	arc, err := wolf.OpenArchive(r, ri.Size())
+	arc, err := wolf.OpenArchiveWithOptions(r, size, opts)
 	if err != nil {
 		return err
 	}
 
-	for _, f := range arc.Files {
-		dst := filepath.Join(dstdir, filepath.FromSlash(f.Path()))
-		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
-			log.Fatalln("DST create error:", err)
-		}
+	return cf.Run(arc.Entries(), dstdir)
+}
 
-		data, err := f.Data()
-		if err != nil {
-			log.Fatalln(err)
+func Pack(srcdir, dstfile, key string, compress, v8 bool) error {
+	return cli.Pack(srcdir, dstfile, func(w io.Writer) (cli.Packer, error) {
+		aw := wolf.NewWriter(w)
+		aw.SetOptions(parseKey(key))
+		aw.SetCompression(compress)
+		if v8 {
+			aw.SetVersion(8)
 		}
 
-		if err := os.WriteFile(dst, data, 0o644); err != nil {
-			log.Fatalln(err)
+		return aw, nil
+	})
+}
+
+// parseKey returns options with either a raw key, if key is 24 hex digits, or a password.
+func parseKey(key string) wolf.Options {
+	if k, err := hex.DecodeString(key); err == nil && len(k) == 12 {
+		return wolf.Options{Key: k}
+	}
+
+	return wolf.Options{Password: key}
+}
+
+func findKey(exefile string, r io.ReaderAt, size int64) (wolf.Options, error) {
+	exe, err := os.Open(exefile)
+	if err != nil {
+		return wolf.Options{}, err
+	}
+	defer exe.Close()
+
+	keys, err := wolf.FindKeys(exe, r, size)
+	if err != nil {
+		return wolf.Options{}, err
+	}
+	if len(keys) == 0 {
+		return wolf.Options{}, fmt.Errorf("no key found in %s", exefile)
+	}
+
+	for _, k := range keys {
+		if k.Key != nil {
+			log.Printf("found key: %x", k.Key)
+		} else {
+			log.Printf("found password: %q", k.Password)
 		}
 	}
 
-	return nil
+	return keys[0], nil
 }
@@ -1,20 +1,19 @@
 package main
 
 import (
-	"archive/zip"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"path/filepath"
 
+	"github.com/kaey/gamearc/internal/cli"
 	"github.com/kaey/gamearc/internal/flagx"
-	"golang.org/x/text/encoding/japanese"
-	"golang.org/x/text/transform"
+	"github.com/kaey/gamearc/zip"
 )
 
 func main() {
+	cf := cli.RegisterFlags()
 	versionFlag := flag.Bool("version", false, "Print version and exit")
 	flag.Usage = flagx.Usage("gamearc-zip [FLAGS] SRCFILE DSTDIR")
 	flag.Parse()
@@ -30,69 +29,44 @@ func main() {
 	}
 
 	dstdir := flag.Arg(1)
-	if dstdir == "" {
+	if dstdir == "" && !cf.List {
 		flagx.Fail("Specify DSTDIR")
 	}
 
-	if err := Main(srcfile, dstdir); err != nil {
+	if err := Main(srcfile, dstdir, cf); err != nil {
 		log.Fatalln(err)
 	}
 }
 
-var shiftJIS = japanese.ShiftJIS.NewDecoder()
-
-func Main(srcfile, dstdir string) error {
-	r, err := os.Open(srcfile)
+func Main(srcfile, dstdir string, cf *cli.Flags) error {
+	r, size, err := cli.Open(srcfile)
 	if err != nil {
 		return err
 	}
+	defer r.Close()
 
-	ri, err := r.Stat()
+	arc, err := zip.OpenArchive(r, size)
 	if err != nil {
 		return err
 	}
 
-	arc, err := zip.NewReader(r, ri.Size())
-	if err != nil {
+	if err := cf.Run(arc.Entries(), dstdir); err != nil {
 		return err
 	}
+	if cf.List {
+		return nil
+	}
 
-	for _, f := range arc.File {
-		path := f.Name
-		if f.NonUTF8 {
-			p, _, err := transform.String(shiftJIS, path)
-			if err != nil {
-				return err
-			}
-			path = p
-		}
-		dst := filepath.Join(dstdir, filepath.FromSlash(path))
-		if f.FileInfo().IsDir() {
-			if err := os.MkdirAll(dst, 0o755); err != nil {
-				log.Fatalln("DST create error:", err)
-			}
-			continue
-		}
-		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
-			log.Fatalln("DST create error:", err)
-		}
-		fi, err := f.Open()
-		if err != nil {
-			return err
-		}
-		fo, err := os.Create(dst)
+	// Directory entries are created too, so that empty directories are kept.
+	for _, dir := range arc.Dirs {
+		match, err := cf.Match(dir)
 		if err != nil {
 			return err
 		}
-
-		if _, err := io.Copy(fo, fi); err != nil {
-			return err
-		}
-
-		if err := fi.Close(); err != nil {
-			return err
+		if !match {
+			continue
 		}
-		if err := fo.Close(); err != nil {
+		if err := os.MkdirAll(filepath.Join(dstdir, filepath.FromSlash(dir)), 0o755); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/kaey/gamearc"
+	"github.com/kaey/gamearc/internal/cli"
+	"github.com/kaey/gamearc/internal/flagx"
+)
+
+const usage = `gamearc [FLAGS] COMMAND SRCFILE [ARGS]
+
+Commands:
+  list SRCFILE            List archive entries
+  extract SRCFILE DSTDIR  Extract all entries into DSTDIR
+  info SRCFILE            Print archive format and summary
+  cat SRCFILE PATH        Write single entry to stdout
+  verify SRCFILE          Read every entry and check its size`
+
+func main() {
+	formatFlag := flag.String("format", "", "Archive format (asar, blorb, rgssad, rpa, wolf, zip), autodetected if empty")
+	ff := cli.RegisterFilterFlags()
+	jsonFlag := flag.Bool("json", false, "Print list output as json")
+	versionFlag := flag.Bool("version", false, "Print version and exit")
+	flag.Usage = flagx.Usage(usage)
+	flag.Parse()
+
+	if *versionFlag {
+		fmt.Fprintf(os.Stderr, "%s", flagx.Version())
+		os.Exit(0)
+	}
+
+	cmd := flag.Arg(0)
+	switch cmd {
+	case "":
+		flagx.Fail("Specify COMMAND")
+	case "list", "info", "verify":
+	case "extract":
+		if flag.Arg(2) == "" {
+			flagx.Fail("Specify SRCFILE and DSTDIR")
+		}
+	case "cat":
+		if flag.Arg(2) == "" {
+			flagx.Fail("Specify SRCFILE and PATH")
+		}
+	default:
+		flagx.Fail(fmt.Sprintf("Unknown command %q", cmd))
+	}
+
+	srcfile := flag.Arg(1)
+	if srcfile == "" {
+		flagx.Fail("Specify SRCFILE")
+	}
+
+	if err := Main(cmd, srcfile, flag.Arg(2), gamearc.Format(*formatFlag), *jsonFlag, ff); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func Main(cmd, srcfile, arg string, format gamearc.Format, asJSON bool, ff *cli.FilterFlags) error {
+	r, size, err := cli.Open(srcfile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	det := &gamearc.Detection{Format: format, Confidence: 100}
+	if format == "" {
+		det, err = gamearc.Detect(r, size)
+	} else {
+		det.Archive, err = gamearc.Open(format, r, size)
+	}
+	if err != nil {
+		return err
+	}
+
+	entries, err := ff.Filter(det.Archive.Entries())
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "list":
+		return cli.List(os.Stdout, entries, asJSON)
+	case "extract":
+		return cli.Extract(entries, arg)
+	case "info":
+		return Info(os.Stdout, det, size)
+	case "cat":
+		return Cat(os.Stdout, det.Archive, arg)
+	case "verify":
+		return Verify(os.Stdout, entries)
+	}
+
+	return fmt.Errorf("unknown command %q", cmd)
+}
+
+func Info(w io.Writer, det *gamearc.Detection, size int64) error {
+	entries := det.Archive.Entries()
+	var total int64
+	for _, e := range entries {
+		total += e.Size()
+	}
+
+	fmt.Fprintf(w, "format:     %s\n", det.Format)
+	fmt.Fprintf(w, "confidence: %d\n", det.Confidence)
+	fmt.Fprintf(w, "size:       %d\n", size)
+	fmt.Fprintf(w, "entries:    %d\n", len(entries))
+	fmt.Fprintf(w, "total:      %d\n", total)
+
+	// Entries with invalid or clashing paths can't be opened by path, see arc.FS.
+	if s, ok := det.Archive.(interface{ Skipped() []gamearc.Entry }); ok {
+		for _, e := range s.Skipped() {
+			fmt.Fprintf(w, "skipped:    %q\n", e.Path())
+		}
+	}
+
+	return nil
+}
+
+func Cat(w io.Writer, a gamearc.Archive, name string) error {
+	for _, e := range a.Entries() {
+		if e.Path() != name {
+			continue
+		}
+
+		r, err := e.Open()
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(w, r)
+		return err
+	}
+
+	return fmt.Errorf("%s: %w", name, os.ErrNotExist)
+}
+
+// Verify reads every entry and reports ones that can't be read completely.
+func Verify(w io.Writer, entries []gamearc.Entry) error {
+	var failed int
+	for _, e := range entries {
+		if err := verifyEntry(e); err != nil {
+			fmt.Fprintf(w, "FAIL %s: %v\n", e.Path(), err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d entries failed verification", failed, len(entries))
+	}
+
+	fmt.Fprintf(w, "OK %d entries\n", len(entries))
+	return nil
+}
+
+func verifyEntry(e gamearc.Entry) error {
+	r, err := e.Open()
+	if err != nil {
+		return err
+	}
+
+	n, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return err
+	}
+	if n != e.Size() {
+		return errors.New("short read")
+	}
+
+	return nil
+}
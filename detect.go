@@ -0,0 +1,186 @@
+package gamearc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/kaey/gamearc/asar"
+	"github.com/kaey/gamearc/blorb"
+	"github.com/kaey/gamearc/rgssad"
+	"github.com/kaey/gamearc/rpa"
+	"github.com/kaey/gamearc/wolf"
+	"github.com/kaey/gamearc/zip"
+)
+
+type Format string
+
+const (
+	Asar   Format = "asar"
+	Blorb  Format = "blorb"
+	Rgssad Format = "rgssad"
+	RPA    Format = "rpa"
+	Wolf   Format = "wolf"
+	Zip    Format = "zip"
+)
+
+var ErrUnknownFormat = errors.New("unknown archive format")
+
+type Detection struct {
+	Format  Format
+	Archive Archive
+
+	// Confidence is in range 1-100. 100 means archive has unambiguous signature,
+	// lower values mean format was guessed from a weaker one.
+	Confidence int
+}
+
+type format struct {
+	format Format
+	sniff  func(header []byte, size int64) int
+	open   func(r io.ReaderAt, size int64) (Archive, error)
+}
+
+var formats = []format{
+	{RPA, sniffRPA, func(r io.ReaderAt, size int64) (Archive, error) { return rpa.OpenArchive(r, size) }},
+	{Rgssad, sniffRgssad, func(r io.ReaderAt, size int64) (Archive, error) { return rgssad.OpenArchive(r, size) }},
+	{Blorb, sniffBlorb, func(r io.ReaderAt, size int64) (Archive, error) { return blorb.OpenArchive(r, size) }},
+	{Asar, sniffAsar, func(r io.ReaderAt, size int64) (Archive, error) { return asar.OpenArchive(r, size) }},
+	{Wolf, sniffWolf, func(r io.ReaderAt, size int64) (Archive, error) { return wolf.OpenArchive(r, size) }},
+	{Zip, sniffZip, func(r io.ReaderAt, size int64) (Archive, error) { return zip.OpenArchive(r, size) }},
+}
+
+// Detect sniffs archive signature and opens it with matching package.
+// When several formats match, they are tried in order of decreasing confidence
+// and the first one that opens successfully is returned.
+func Detect(r io.ReaderAt, size int64) (*Detection, error) {
+	header := make([]byte, 64)
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	header = header[:n]
+
+	var candidates []Detection
+	for _, f := range formats {
+		if c := f.sniff(header, size); c > 0 {
+			candidates = append(candidates, Detection{Format: f.format, Confidence: c})
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b Detection) int {
+		return b.Confidence - a.Confidence
+	})
+
+	var errs []error
+	for _, c := range candidates {
+		a, err := Open(c.Format, r, size)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Format, err))
+			continue
+		}
+
+		c.Archive = a
+		return &c, nil
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %w", ErrUnknownFormat, errors.Join(errs...))
+	}
+
+	return nil, ErrUnknownFormat
+}
+
+// Open opens archive of a known format.
+func Open(f Format, r io.ReaderAt, size int64) (Archive, error) {
+	for _, ff := range formats {
+		if ff.format == f {
+			return ff.open(r, size)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+}
+
+func sniffRPA(header []byte, _ int64) int {
+	if rpa.HasMagic(header) {
+		return 100
+	}
+	return 0
+}
+
+func sniffRgssad(header []byte, _ int64) int {
+	if bytes.HasPrefix(header, []byte("RGSSAD\000")) {
+		return 100
+	}
+	return 0
+}
+
+func sniffBlorb(header []byte, _ int64) int {
+	if len(header) >= 12 && bytes.Equal(header[0:4], []byte("FORM")) && bytes.Equal(header[8:12], []byte("IFRS")) {
+		return 100
+	}
+	return 0
+}
+
+// sniffAsar checks pickle header, which is a sequence of lengths without any magic bytes.
+func sniffAsar(header []byte, size int64) int {
+	if len(header) < 17 {
+		return 0
+	}
+
+	le := binary.LittleEndian
+	if le.Uint32(header[0:4]) != 4 {
+		return 0
+	}
+
+	indexLength := le.Uint32(header[4:8])
+	jsonLength := le.Uint32(header[12:16])
+	if int64(indexLength)+8 > size || jsonLength > indexLength {
+		return 0
+	}
+
+	if header[16] == '{' {
+		return 90
+	}
+	return 30
+}
+
+// sniffWolf decrypts first bytes of the header with the key derived from the header itself.
+func sniffWolf(header []byte, _ int64) int {
+	if len(header) < 32 {
+		return 0
+	}
+
+	// Version 8 header is not encrypted.
+	if bytes.HasPrefix(header, []byte("DX\x08\x00")) {
+		return 90
+	}
+
+	// Versions 6 and 7: first 4 bytes of the key are stored at offset 12.
+	if header[0]^header[12] == 'D' && header[1]^header[13] == 'X' {
+		return 80
+	}
+
+	// Version 5: codepage at offset 24 is encrypted with the same key bytes as magic and version.
+	le := binary.LittleEndian
+	magic := le.Uint32([]byte{'D', 'X', 5, 0})
+	codepage := le.Uint32(header[24:28]) ^ le.Uint32(header[0:4]) ^ magic
+	if wolf.Encoding(int(codepage)) != nil {
+		return 50
+	}
+	return 0
+}
+
+func sniffZip(header []byte, _ int64) int {
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\003\004")):
+		return 90
+	case bytes.HasPrefix(header, []byte("PK\005\006")):
+		// Empty archive.
+		return 60
+	}
+	return 0
+}
@@ -0,0 +1,27 @@
+package gamearc
+
+import "github.com/kaey/gamearc/internal/arc"
+
+// Match reports whether name matches the shell pattern.
+// Pattern syntax is the same as in path.Match, except that ** as a whole
+// path element matches zero or more directories.
+func Match(pattern, name string) (bool, error) {
+	return arc.Match(pattern, name)
+}
+
+// Filter returns entries which match any of include patterns and none of exclude patterns.
+// Empty include list matches everything.
+func Filter(entries []Entry, include, exclude []string) ([]Entry, error) {
+	return arc.Filter(entries, include, exclude)
+}
+
+// SortByPath sorts entries by path.
+func SortByPath(entries []Entry) {
+	arc.SortByPath(entries)
+}
+
+// SortByOffset sorts entries by offset of their data in the archive, entries with the same offset by path.
+// Entries which don't report offset are placed last.
+func SortByOffset(entries []Entry) {
+	arc.SortByOffset(entries)
+}
@@ -0,0 +1,44 @@
+// Package gamearc provides a common interface over the archive formats
+// supported by its subpackages.
+package gamearc
+
+import (
+	"io/fs"
+
+	"github.com/kaey/gamearc/asar"
+	"github.com/kaey/gamearc/blorb"
+	"github.com/kaey/gamearc/internal/arc"
+	"github.com/kaey/gamearc/rgssad"
+	"github.com/kaey/gamearc/rpa"
+	"github.com/kaey/gamearc/wolf"
+	"github.com/kaey/gamearc/zip"
+)
+
+type (
+	Archive = arc.Archive
+	Entry   = arc.Entry
+)
+
+var (
+	_ Archive = (*asar.Archive)(nil)
+	_ Archive = (*blorb.Archive)(nil)
+	_ Archive = (*rgssad.Archive)(nil)
+	_ Archive = (*rpa.Archive)(nil)
+	_ Archive = (*wolf.Archive)(nil)
+	_ Archive = (*zip.Archive)(nil)
+)
+
+var (
+	_ fsys = (*asar.Archive)(nil)
+	_ fsys = (*blorb.Archive)(nil)
+	_ fsys = (*rgssad.Archive)(nil)
+	_ fsys = (*rpa.Archive)(nil)
+	_ fsys = (*wolf.Archive)(nil)
+	_ fsys = (*zip.Archive)(nil)
+)
+
+type fsys interface {
+	fs.ReadDirFS
+	fs.StatFS
+	fs.ReadFileFS
+}
@@ -0,0 +1,18 @@
+// Package arc holds the types shared by all archive packages.
+// They are re-exported by the top-level gamearc package.
+package arc
+
+import "io"
+
+type Archive interface {
+	Entries() []Entry
+}
+
+type Entry interface {
+	Path() string
+	Size() int64
+	Open() (io.ReadSeeker, error)
+
+	// Meta returns format-specific details, nil if there are none.
+	Meta() map[string]any
+}
@@ -0,0 +1,77 @@
+package arc
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Match reports whether name matches the shell pattern.
+// Pattern syntax is the same as in path.Match, except that ** as a whole
+// path element matches zero or more directories.
+func Match(pattern, name string) (bool, error) {
+	pat := strings.Split(pattern, "/")
+	for _, p := range pat {
+		if _, err := path.Match(p, ""); err != nil {
+			return false, fmt.Errorf("%q: %w", pattern, err)
+		}
+	}
+
+	return matchElems(pat, strings.Split(name, "/")), nil
+}
+
+func matchElems(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := range len(name) + 1 {
+				if matchElems(pat[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// Filter returns entries which match any of include patterns and none of exclude patterns.
+// Empty include list matches everything.
+func Filter(entries []Entry, include, exclude []string) ([]Entry, error) {
+	for _, p := range append(include[:len(include):len(include)], exclude...) {
+		if _, err := Match(p, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	var res []Entry
+	for _, e := range entries {
+		if len(include) > 0 && !matchAny(include, e.Path()) {
+			continue
+		}
+		if matchAny(exclude, e.Path()) {
+			continue
+		}
+		res = append(res, e)
+	}
+
+	return res, nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := Match(p, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,283 @@
+package arc
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FS implements fs.FS over a flat list of entries.
+// Directories are synthesized from entry paths.
+// Archive types embed it to implement both Archive and fs.FS.
+type FS struct {
+	entries []Entry
+	skipped []Entry
+	files   map[string]Entry
+	dirs    map[string][]fs.DirEntry
+}
+
+// NewFS returns FS over entries. Entries which can't be opened by path are kept in Entries
+// and also reported by Skipped.
+func NewFS(entries []Entry) *FS {
+	fsys := &FS{
+		entries: entries,
+		files:   make(map[string]Entry, len(entries)),
+		dirs:    map[string][]fs.DirEntry{".": nil},
+	}
+
+	for _, e := range entries {
+		if !fsys.add(e) {
+			fsys.skipped = append(fsys.skipped, e)
+		}
+	}
+
+	for _, d := range fsys.dirs {
+		slices.SortFunc(d, func(a, b fs.DirEntry) int {
+			return strings.Compare(a.Name(), b.Name())
+		})
+	}
+
+	return fsys
+}
+
+// Entries returns a slice of pointers to files, for archives which keep their files in a slice.
+func Entries[F any, P interface {
+	*F
+	Entry
+}](files []F) []Entry {
+	entries := make([]Entry, len(files))
+	for i := range files {
+		entries[i] = P(&files[i])
+	}
+
+	return entries
+}
+
+// Entries returns all entries in archive order, including skipped ones.
+func (fsys *FS) Entries() []Entry {
+	return slices.Clone(fsys.entries)
+}
+
+// Skipped returns entries which are not present in the file system, because their path is not valid
+// according to fs.ValidPath, is used by another entry or has another entry as its parent directory.
+func (fsys *FS) Skipped() []Entry {
+	return slices.Clone(fsys.skipped)
+}
+
+// add adds e to the file system and reports whether its path was usable.
+func (fsys *FS) add(e Entry) bool {
+	p := e.Path()
+	if !fs.ValidPath(p) || p == "." {
+		return false
+	}
+	if _, ok := fsys.files[p]; ok {
+		return false
+	}
+	if _, ok := fsys.dirs[p]; ok {
+		return false
+	}
+	if fsys.underFile(p) {
+		return false
+	}
+	fsys.files[p] = e
+	fsys.addDirEntry(p, &fileInfo{name: path.Base(p), entry: e})
+
+	return true
+}
+
+// underFile reports whether any parent of p is a file.
+func (fsys *FS) underFile(p string) bool {
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		if _, ok := fsys.files[dir]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addDirEntry adds fi to the listing of its parent, creating missing parents along the way.
+func (fsys *FS) addDirEntry(p string, fi *fileInfo) {
+	for {
+		dir := path.Dir(p)
+		_, ok := fsys.dirs[dir]
+		fsys.dirs[dir] = append(fsys.dirs[dir], fs.FileInfoToDirEntry(fi))
+		if ok {
+			return
+		}
+
+		p, fi = dir, &fileInfo{name: path.Base(dir), dir: true}
+	}
+}
+
+func (fsys *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if e, ok := fsys.files[name]; ok {
+		r, err := e.Open()
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		return &file{ReadSeeker: r, info: &fileInfo{name: path.Base(name), entry: e}}, nil
+	}
+
+	if d, ok := fsys.dirs[name]; ok {
+		return &dir{info: &fileInfo{name: path.Base(name), dir: true}, entries: d}, nil
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	d, ok := fsys.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return slices.Clone(d), nil
+}
+
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if e, ok := fsys.files[name]; ok {
+		return &fileInfo{name: path.Base(name), entry: e}, nil
+	}
+
+	if _, ok := fsys.dirs[name]; ok {
+		return &fileInfo{name: path.Base(name), dir: true}, nil
+	}
+
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+
+	e, ok := fsys.files[name]
+	if !ok {
+		if _, ok := fsys.dirs[name]; ok {
+			return nil, &fs.PathError{Op: "readfile", Path: name, Err: errors.New("is a directory")}
+		}
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrNotExist}
+	}
+
+	r, err := e.Open()
+	if err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+	}
+
+	data := make([]byte, e.Size())
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+	}
+
+	return data, nil
+}
+
+type fileInfo struct {
+	name  string
+	dir   bool
+	entry Entry
+}
+
+func (fi *fileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *fileInfo) Size() int64 {
+	if fi.dir {
+		return 0
+	}
+	return fi.entry.Size()
+}
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+// ModTime returns modification time of entries which have ModTime method.
+func (fi *fileInfo) ModTime() time.Time {
+	if m, ok := fi.entry.(interface{ ModTime() time.Time }); ok && !fi.dir {
+		return m.ModTime()
+	}
+	return time.Time{}
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return fi.dir
+}
+
+// Sys returns underlying Entry for files and nil for directories.
+func (fi *fileInfo) Sys() any {
+	if fi.dir {
+		return nil
+	}
+	return fi.entry
+}
+
+type file struct {
+	io.ReadSeeker
+	info *fileInfo
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *file) Close() error {
+	return nil
+}
+
+type dir struct {
+	info    *fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *dir) Close() error {
+	return nil
+}
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return slices.Clone(rest), nil
+	}
+
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(rest))
+	d.offset += n
+
+	return slices.Clone(rest[:n]), nil
+}
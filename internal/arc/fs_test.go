@@ -0,0 +1,55 @@
+package arc
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/fstest"
+)
+
+type testFile struct {
+	path string
+	data string
+}
+
+func (f *testFile) Path() string                 { return f.path }
+func (f *testFile) Size() int64                  { return int64(len(f.data)) }
+func (f *testFile) Open() (io.ReadSeeker, error) { return bytes.NewReader([]byte(f.data)), nil }
+func (f *testFile) Meta() map[string]any         { return nil }
+
+func TestFS(t *testing.T) {
+	files := []testFile{
+		{"a/b.txt", "b"},
+		{"a/c/d.txt", "d"},
+		{"e.txt", "e"},
+		{"e.txt", "duplicate"},
+		{"/abs.txt", "invalid"},
+		{"a/../f.txt", "invalid"},
+		{"a", "clashes with directory"},
+		{"e.txt/g.txt", "under file"},
+	}
+	fsys := NewFS(Entries(files))
+
+	if err := fstest.TestFS(fsys, "a/b.txt", "a/c/d.txt", "e.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(fsys.Entries()); got != len(files) {
+		t.Errorf("got %d entries, want %d", got, len(files))
+	}
+
+	skipped := fsys.Skipped()
+	if len(skipped) != 5 {
+		t.Fatalf("got %d skipped entries, want 5", len(skipped))
+	}
+	for i, e := range skipped {
+		if e != Entry(&files[3+i]) {
+			t.Errorf("skipped %d: got %q, want %q", i, e.Path(), files[3+i].path)
+		}
+	}
+
+	data, err := fsys.ReadFile("e.txt")
+	if err != nil || string(data) != "e" {
+		t.Errorf("e.txt: got %q, %v; first entry with the same path should win", data, err)
+	}
+}
@@ -0,0 +1,32 @@
+package arc
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// SortByPath sorts entries by path.
+func SortByPath(entries []Entry) {
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		return strings.Compare(a.Path(), b.Path())
+	})
+}
+
+// SortByOffset sorts entries by offset of their data in the archive, entries with the same offset by path.
+// Entries which don't report offset are placed last.
+func SortByOffset(entries []Entry) {
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		if c := cmp.Compare(offset(a), offset(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Path(), b.Path())
+	})
+}
+
+func offset(e Entry) uint64 {
+	if o, ok := e.(interface{ Offset() int64 }); ok && o.Offset() >= 0 {
+		return uint64(o.Offset())
+	}
+	return ^uint64(0)
+}
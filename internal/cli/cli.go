@@ -0,0 +1,226 @@
+// Package cli contains code shared by commands.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kaey/gamearc/internal/arc"
+)
+
+// Open opens srcfile, caller must close returned file.
+func Open(srcfile string) (*os.File, int64, error) {
+	r, err := os.Open(srcfile)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ri, err := r.Stat()
+	if err != nil {
+		r.Close()
+		return nil, 0, err
+	}
+
+	return r, ri.Size(), nil
+}
+
+// Extract writes entries into dstdir, creating subdirectories as needed.
+// Entries which fail with errors.ErrUnsupported are logged and skipped, an error is returned
+// after the rest are written.
+func Extract(entries []arc.Entry, dstdir string) error {
+	skipped := 0
+	for _, e := range entries {
+		if !fs.ValidPath(e.Path()) {
+			return fmt.Errorf("bad path: %q", e.Path())
+		}
+
+		dstfile := filepath.Join(dstdir, filepath.FromSlash(e.Path()))
+		if err := os.MkdirAll(filepath.Dir(dstfile), 0o755); err != nil {
+			return err
+		}
+
+		if err := WriteFile(e, dstfile); err != nil {
+			if errors.Is(err, errors.ErrUnsupported) {
+				log.Printf("skipped %s: %v", e.Path(), err)
+				skipped++
+				continue
+			}
+			return fmt.Errorf("%s: %w", e.Path(), err)
+		}
+	}
+
+	if skipped > 0 {
+		return fmt.Errorf("%d of %d entries skipped", skipped, len(entries))
+	}
+
+	return nil
+}
+
+// WriteFile writes contents of a single entry into dstfile.
+// Modification time and read-only attribute are applied if the entry has them, hidden attribute is ignored.
+func WriteFile(e arc.Entry, dstfile string) error {
+	r, err := e.Open()
+	if err != nil {
+		return err
+	}
+
+	ro, _ := e.(interface{ ReadOnly() bool })
+	readOnly := ro != nil && ro.ReadOnly()
+	if readOnly {
+		// File extracted earlier can't be opened for writing.
+		if err := os.Remove(dstfile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	w, err := os.Create(dstfile)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	// Keep modification time of entries which have it, access time is left as is.
+	if m, ok := e.(interface{ ModTime() time.Time }); ok && !m.ModTime().IsZero() {
+		if err := os.Chtimes(dstfile, time.Time{}, m.ModTime()); err != nil {
+			return err
+		}
+	}
+
+	if readOnly {
+		return os.Chmod(dstfile, 0o444)
+	}
+
+	return nil
+}
+
+// Flags are common flags of per-format commands.
+type Flags struct {
+	List bool
+	JSON bool
+	FilterFlags
+}
+
+// RegisterFlags registers common flags on flag.CommandLine.
+func RegisterFlags() *Flags {
+	f := new(Flags)
+	flag.BoolVar(&f.List, "list", false, "List archive contents instead of extracting, DSTDIR is not needed")
+	flag.BoolVar(&f.JSON, "json", false, "Print -list output as json")
+	f.FilterFlags.register()
+
+	return f
+}
+
+// Run either lists or extracts entries depending on flags.
+func (f *Flags) Run(entries []arc.Entry, dstdir string) error {
+	entries, err := f.Filter(entries)
+	if err != nil {
+		return err
+	}
+
+	if f.List {
+		return List(os.Stdout, entries, f.JSON)
+	}
+
+	return Extract(entries, dstdir)
+}
+
+// FilterFlags are -include, -exclude and -sort flags.
+type FilterFlags struct {
+	Include []string
+	Exclude []string
+	Sort    string
+}
+
+// RegisterFilterFlags registers -include and -exclude flags on flag.CommandLine.
+func RegisterFilterFlags() *FilterFlags {
+	f := new(FilterFlags)
+	f.register()
+
+	return f
+}
+
+// RegisterMatchFlags registers only -include and -exclude flags, for commands which process plain files instead of archive entries.
+func RegisterMatchFlags() *FilterFlags {
+	f := new(FilterFlags)
+	f.registerMatch()
+
+	return f
+}
+
+func (f *FilterFlags) register() {
+	f.registerMatch()
+	flag.StringVar(&f.Sort, "sort", "", "Sort entries by `ORDER` (path or offset), archive order if empty")
+}
+
+func (f *FilterFlags) registerMatch() {
+	flag.Var((*stringsFlag)(&f.Include), "include", "Only process paths matching glob `PATTERN` (** matches any number of directories), can be repeated")
+	flag.Var((*stringsFlag)(&f.Exclude), "exclude", "Skip paths matching glob `PATTERN`, can be repeated")
+}
+
+// Match reports whether name matches any of -include patterns and none of -exclude patterns.
+func (f *FilterFlags) Match(name string) (bool, error) {
+	ok := len(f.Include) == 0
+	for _, p := range f.Include {
+		m, err := arc.Match(p, name)
+		if err != nil {
+			return false, err
+		}
+		ok = ok || m
+	}
+	for _, p := range f.Exclude {
+		m, err := arc.Match(p, name)
+		if err != nil {
+			return false, err
+		}
+		ok = ok && !m
+	}
+
+	return ok, nil
+}
+
+// Filter returns entries matching -include and -exclude, sorted according to -sort.
+func (f *FilterFlags) Filter(entries []arc.Entry) ([]arc.Entry, error) {
+	entries, err := arc.Filter(entries, f.Include, f.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	switch f.Sort {
+	case "":
+	case "path":
+		arc.SortByPath(entries)
+	case "offset":
+		arc.SortByOffset(entries)
+	default:
+		return nil, fmt.Errorf("unknown sort order %q", f.Sort)
+	}
+
+	return entries, nil
+}
+
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
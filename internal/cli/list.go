@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kaey/gamearc/internal/arc"
+)
+
+type listEntry struct {
+	Path   string         `json:"path"`
+	Size   int64          `json:"size"`
+	Offset int64          `json:"offset"`
+	Meta   map[string]any `json:"meta,omitempty"`
+}
+
+// List prints path, size, offset and format-specific info of every entry,
+// either as a table or as a json array.
+func List(w io.Writer, entries []arc.Entry, asJSON bool) error {
+	list := make([]listEntry, len(entries))
+	for i, e := range entries {
+		list[i] = listEntry{
+			Path:   e.Path(),
+			Size:   e.Size(),
+			Offset: -1,
+			Meta:   e.Meta(),
+		}
+		if o, ok := e.(interface{ Offset() int64 }); ok {
+			list[i].Offset = o.Offset()
+		}
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "OFFSET\tSIZE\tPATH\tINFO\n")
+	for _, e := range list {
+		info := make([]string, 0, len(e.Meta))
+		for _, k := range slices.Sorted(maps.Keys(e.Meta)) {
+			info = append(info, fmt.Sprintf("%s=%v", k, e.Meta[k]))
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\n", e.Offset, e.Size, e.Path, strings.Join(info, " "))
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"io"
+	"io/fs"
+	"os"
+)
+
+// Packer is implemented by archive writers.
+type Packer interface {
+	AddFS(fsys fs.FS) error
+	Close() error
+}
+
+// Pack writes all files from srcdir into archive dstfile.
+func Pack(srcdir, dstfile string, newPacker func(w io.Writer) (Packer, error)) error {
+	w, err := os.Create(dstfile)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	p, err := newPacker(w)
+	if err != nil {
+		return err
+	}
+
+	if err := p.AddFS(os.DirFS(srcdir)); err != nil {
+		return err
+	}
+
+	if err := p.Close(); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
@@ -0,0 +1,835 @@
+// Package pickle decodes python pickles into generic values.
+//
+// Unlike github.com/kisielk/og-rek it supports BUILD, NEWOBJ and other opcodes
+// used to pickle class instances, which are represented by Object without running any python code.
+//
+// Python values are decoded as: None - nil, bool, int - int64 or *big.Int, float - float64,
+// str and unicode - string, bytes - Bytes, tuple - Tuple, list and set - *List, dict - *Dict,
+// class reference - Class, class instance - *Object.
+package pickle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Bytes is python 3 bytes or bytearray.
+type Bytes []byte
+
+// Tuple is python tuple.
+type Tuple []any
+
+// List is python list, set or frozenset. It is a pointer so that memoized references see appended items.
+type List struct {
+	Items []any
+}
+
+// Dict is python dict, keys keep insertion order.
+type Dict struct {
+	Keys   []any
+	Values []any
+}
+
+// Get returns value of a string key.
+func (d *Dict) Get(key string) (any, bool) {
+	for i, k := range d.Keys {
+		if k == any(key) {
+			return d.Values[i], true
+		}
+	}
+
+	return nil, false
+}
+
+func (d *Dict) set(key, value any) {
+	for i, k := range d.Keys {
+		if equal(k, key) {
+			d.Values[i] = value
+			return
+		}
+	}
+
+	d.Keys = append(d.Keys, key)
+	d.Values = append(d.Values, value)
+}
+
+// Class is a reference to python class or function.
+type Class struct {
+	Module, Name string
+}
+
+func (c Class) String() string {
+	return c.Module + "." + c.Name
+}
+
+// Object is an instance of a class or a result of a function call.
+type Object struct {
+	Class Class
+	Args  Tuple
+
+	// State is set by BUILD, it is usually a dict of attributes,
+	// a tuple of dict and a dict of slots, or whatever __getstate__ returned.
+	State any
+
+	// Items and Dict hold contents of list and dict subclasses.
+	Items []any
+	Dict  *Dict
+}
+
+// Get returns attribute name from State dict or slots, nil if there is no such attribute.
+func (o *Object) Get(name string) any {
+	switch s := o.State.(type) {
+	case *Dict:
+		v, _ := s.Get(name)
+		return v
+	case Tuple:
+		for _, d := range s {
+			if d, ok := d.(*Dict); ok {
+				if v, ok := d.Get(name); ok {
+					return v
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+type decoder struct {
+	r     *bufio.Reader
+	stack []any
+	marks []int
+	memo  map[int]any
+}
+
+// Load decodes a single pickle from r.
+func Load(r io.Reader) (any, error) {
+	d := &decoder{r: bufio.NewReader(r), memo: make(map[int]any)}
+	for {
+		op, err := d.r.ReadByte()
+		if err != nil {
+			return nil, unexpected(err)
+		}
+		if op == '.' {
+			return d.pop()
+		}
+		if err := d.op(op); err != nil {
+			return nil, fmt.Errorf("pickle: opcode %q: %w", op, err)
+		}
+	}
+}
+
+var errStack = errors.New("stack underflow")
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (d *decoder) push(v any) {
+	d.stack = append(d.stack, v)
+}
+
+func (d *decoder) pop() (any, error) {
+	if len(d.stack) == 0 {
+		return nil, errStack
+	}
+	v := d.stack[len(d.stack)-1]
+	d.stack = d.stack[:len(d.stack)-1]
+
+	return v, nil
+}
+
+func (d *decoder) top() (any, error) {
+	if len(d.stack) == 0 {
+		return nil, errStack
+	}
+	return d.stack[len(d.stack)-1], nil
+}
+
+// popMark pops items pushed after the last MARK.
+func (d *decoder) popMark() ([]any, error) {
+	if len(d.marks) == 0 {
+		return nil, errors.New("no mark")
+	}
+	n := d.marks[len(d.marks)-1]
+	d.marks = d.marks[:len(d.marks)-1]
+	// Items below the mark could be popped already.
+	if n > len(d.stack) {
+		return nil, errStack
+	}
+	items := append([]any(nil), d.stack[n:]...)
+	d.stack = d.stack[:n]
+
+	return items, nil
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	const maxLength = 1 << 30
+	if n < 0 || n > maxLength {
+		return nil, fmt.Errorf("bad length %d", n)
+	}
+
+	// Length comes from input, so large buffers grow as data is read rather than being allocated upfront.
+	const maxAlloc = 64 << 10
+	if n <= maxAlloc {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(d.r, b); err != nil {
+			return nil, unexpected(err)
+		}
+		return b, nil
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, d.r, int64(n)); err != nil {
+		return nil, unexpected(err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (d *decoder) readUint(n int) (uint64, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return 0, err
+	}
+
+	var v uint64
+	for i := range b {
+		v |= uint64(b[i]) << (8 * i)
+	}
+
+	return v, nil
+}
+
+func (d *decoder) readLine() (string, error) {
+	line, err := d.r.ReadString('\n')
+	if err != nil {
+		return "", unexpected(err)
+	}
+
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+func (d *decoder) op(op byte) error {
+	switch op {
+	case '(': // MARK
+		d.marks = append(d.marks, len(d.stack))
+	case '0': // POP
+		_, err := d.pop()
+		return err
+	case '1': // POP_MARK
+		_, err := d.popMark()
+		return err
+	case '2': // DUP
+		v, err := d.top()
+		if err != nil {
+			return err
+		}
+		d.push(v)
+	case '\x80': // PROTO
+		_, err := d.r.ReadByte()
+		return unexpected(err)
+	case '\x95': // FRAME
+		_, err := d.read(8)
+		return err
+
+	case 'N': // NONE
+		d.push(nil)
+	case '\x88': // NEWTRUE
+		d.push(true)
+	case '\x89': // NEWFALSE
+		d.push(false)
+	case 'I': // INT
+		line, err := d.readLine()
+		if err != nil {
+			return err
+		}
+		switch line {
+		case "00":
+			d.push(false)
+		case "01":
+			d.push(true)
+		default:
+			return d.pushInt(line)
+		}
+	case 'L': // LONG
+		line, err := d.readLine()
+		if err != nil {
+			return err
+		}
+		return d.pushInt(strings.TrimSuffix(line, "L"))
+	case 'J': // BININT
+		v, err := d.readUint(4)
+		d.push(int64(int32(v)))
+		return err
+	case 'K': // BININT1
+		v, err := d.readUint(1)
+		d.push(int64(v))
+		return err
+	case 'M': // BININT2
+		v, err := d.readUint(2)
+		d.push(int64(v))
+		return err
+	case '\x8a', '\x8b': // LONG1, LONG4
+		size := 1
+		if op == '\x8b' {
+			size = 4
+		}
+		n, err := d.readUint(size)
+		if err != nil {
+			return err
+		}
+		b, err := d.read(int(n))
+		if err != nil {
+			return err
+		}
+		d.push(decodeLong(b))
+	case 'F': // FLOAT
+		line, err := d.readLine()
+		if err != nil {
+			return err
+		}
+		v, err := strconv.ParseFloat(line, 64)
+		d.push(v)
+		return err
+	case 'G': // BINFLOAT
+		b, err := d.read(8)
+		if err != nil {
+			return err
+		}
+		d.push(math.Float64frombits(binary.BigEndian.Uint64(b)))
+
+	case 'S': // STRING
+		line, err := d.readLine()
+		if err != nil {
+			return err
+		}
+		s, err := unquote(line)
+		d.push(s)
+		return err
+	case 'V': // UNICODE
+		line, err := d.readLine()
+		if err != nil {
+			return err
+		}
+		d.push(decodeRawUnicodeEscape(line))
+	case 'T', 'X', 'U', '\x8c', '\x8d': // BINSTRING, BINUNICODE, SHORT_BINSTRING, SHORT_BINUNICODE, BINUNICODE8
+		size := map[byte]int{'T': 4, 'X': 4, 'U': 1, '\x8c': 1, '\x8d': 8}[op]
+		n, err := d.readUint(size)
+		if err != nil {
+			return err
+		}
+		b, err := d.read(int(n))
+		d.push(string(b))
+		return err
+	case 'B', 'C', '\x8e', '\x96': // BINBYTES, SHORT_BINBYTES, BINBYTES8, BYTEARRAY8
+		size := map[byte]int{'B': 4, 'C': 1, '\x8e': 8, '\x96': 8}[op]
+		n, err := d.readUint(size)
+		if err != nil {
+			return err
+		}
+		b, err := d.read(int(n))
+		d.push(Bytes(b))
+		return err
+
+	case ')': // EMPTY_TUPLE
+		d.push(Tuple{})
+	case 't': // TUPLE
+		items, err := d.popMark()
+		d.push(Tuple(items))
+		return err
+	case '\x85', '\x86', '\x87': // TUPLE1, TUPLE2, TUPLE3
+		n := int(op-'\x85') + 1
+		if len(d.stack) < n {
+			return errStack
+		}
+		t := append(Tuple(nil), d.stack[len(d.stack)-n:]...)
+		d.stack = d.stack[:len(d.stack)-n]
+		d.push(t)
+	case ']': // EMPTY_LIST
+		d.push(&List{})
+	case '\x8f': // EMPTY_SET
+		d.push(&List{})
+	case 'l': // LIST
+		items, err := d.popMark()
+		d.push(&List{Items: items})
+		return err
+	case '\x91': // FROZENSET
+		items, err := d.popMark()
+		d.push(&List{Items: items})
+		return err
+	case 'a': // APPEND
+		v, err := d.pop()
+		if err != nil {
+			return err
+		}
+		return d.appendItems([]any{v})
+	case 'e', '\x90': // APPENDS, ADDITEMS
+		items, err := d.popMark()
+		if err != nil {
+			return err
+		}
+		return d.appendItems(items)
+	case '}': // EMPTY_DICT
+		d.push(&Dict{})
+	case 'd': // DICT
+		items, err := d.popMark()
+		if err != nil {
+			return err
+		}
+		dict := &Dict{}
+		d.push(dict)
+		return setItems(dict, items)
+	case 's': // SETITEM
+		if len(d.stack) < 2 {
+			return errStack
+		}
+		items := d.stack[len(d.stack)-2:]
+		d.stack = d.stack[:len(d.stack)-2]
+		return d.setItems(items)
+	case 'u': // SETITEMS
+		items, err := d.popMark()
+		if err != nil {
+			return err
+		}
+		return d.setItems(items)
+
+	case 'p': // PUT
+		line, err := d.readLine()
+		if err != nil {
+			return err
+		}
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			return err
+		}
+		return d.put(n)
+	case 'q', 'r': // BINPUT, LONG_BINPUT
+		n, err := d.readUint(map[byte]int{'q': 1, 'r': 4}[op])
+		if err != nil {
+			return err
+		}
+		return d.put(int(n))
+	case '\x94': // MEMOIZE
+		return d.put(len(d.memo))
+	case 'g': // GET
+		line, err := d.readLine()
+		if err != nil {
+			return err
+		}
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			return err
+		}
+		return d.get(n)
+	case 'h', 'j': // BINGET, LONG_BINGET
+		n, err := d.readUint(map[byte]int{'h': 1, 'j': 4}[op])
+		if err != nil {
+			return err
+		}
+		return d.get(int(n))
+
+	case 'c': // GLOBAL
+		module, err := d.readLine()
+		if err != nil {
+			return err
+		}
+		name, err := d.readLine()
+		d.push(Class{Module: module, Name: name})
+		return err
+	case '\x93': // STACK_GLOBAL
+		name, err := d.pop()
+		if err != nil {
+			return err
+		}
+		module, err := d.pop()
+		if err != nil {
+			return err
+		}
+		m, ok1 := module.(string)
+		n, ok2 := name.(string)
+		if !ok1 || !ok2 {
+			return fmt.Errorf("expected module and name strings, got %T and %T", module, name)
+		}
+		d.push(Class{Module: m, Name: n})
+	case 'R': // REDUCE
+		args, err := d.pop()
+		if err != nil {
+			return err
+		}
+		class, err := d.pop()
+		if err != nil {
+			return err
+		}
+		return d.call(class, args)
+	case '\x81': // NEWOBJ
+		args, err := d.pop()
+		if err != nil {
+			return err
+		}
+		class, err := d.pop()
+		if err != nil {
+			return err
+		}
+		return d.newObject(class, args)
+	case '\x92': // NEWOBJ_EX
+		if _, err := d.pop(); err != nil { // Keyword arguments are ignored.
+			return err
+		}
+		args, err := d.pop()
+		if err != nil {
+			return err
+		}
+		class, err := d.pop()
+		if err != nil {
+			return err
+		}
+		return d.newObject(class, args)
+	case 'i': // INST
+		module, err := d.readLine()
+		if err != nil {
+			return err
+		}
+		name, err := d.readLine()
+		if err != nil {
+			return err
+		}
+		args, err := d.popMark()
+		if err != nil {
+			return err
+		}
+		d.push(&Object{Class: Class{Module: module, Name: name}, Args: args})
+	case 'o': // OBJ
+		items, err := d.popMark()
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return errStack
+		}
+		return d.newObject(items[0], Tuple(items[1:]))
+	case 'b': // BUILD
+		state, err := d.pop()
+		if err != nil {
+			return err
+		}
+		v, err := d.top()
+		if err != nil {
+			return err
+		}
+		o, ok := v.(*Object)
+		if !ok {
+			return fmt.Errorf("build of %T", v)
+		}
+		o.State = state
+
+	default:
+		return errors.New("unsupported opcode")
+	}
+
+	return nil
+}
+
+func (d *decoder) pushInt(s string) error {
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		d.push(v)
+		return nil
+	}
+
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return fmt.Errorf("bad int %q", s)
+	}
+	d.push(v)
+
+	return nil
+}
+
+// decodeLong decodes little-endian two's complement integer.
+func decodeLong(b []byte) any {
+	if len(b) == 0 {
+		return int64(0)
+	}
+
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	v := new(big.Int).SetBytes(be)
+	if b[len(b)-1]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	if v.IsInt64() {
+		return v.Int64()
+	}
+
+	return v
+}
+
+func (d *decoder) put(n int) error {
+	v, err := d.top()
+	if err != nil {
+		return err
+	}
+	d.memo[n] = v
+
+	return nil
+}
+
+func (d *decoder) get(n int) error {
+	v, ok := d.memo[n]
+	if !ok {
+		return fmt.Errorf("memo %d not found", n)
+	}
+	d.push(v)
+
+	return nil
+}
+
+func (d *decoder) appendItems(items []any) error {
+	v, err := d.top()
+	if err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case *List:
+		v.Items = append(v.Items, items...)
+	case *Object:
+		v.Items = append(v.Items, items...)
+	default:
+		return fmt.Errorf("append to %T", v)
+	}
+
+	return nil
+}
+
+func (d *decoder) setItems(items []any) error {
+	v, err := d.top()
+	if err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case *Dict:
+		return setItems(v, items)
+	case *Object:
+		if v.Dict == nil {
+			v.Dict = &Dict{}
+		}
+		return setItems(v.Dict, items)
+	default:
+		return fmt.Errorf("setitem of %T", v)
+	}
+}
+
+func setItems(d *Dict, items []any) error {
+	if len(items)%2 != 0 {
+		return errors.New("odd number of dict items")
+	}
+	for i := 0; i < len(items); i += 2 {
+		d.set(items[i], items[i+1])
+	}
+
+	return nil
+}
+
+// call handles REDUCE, builtin types are converted to their values, anything else becomes an Object.
+func (d *decoder) call(class, args any) error {
+	c, ok := class.(Class)
+	if !ok {
+		return fmt.Errorf("call of %T", class)
+	}
+	t, ok := args.(Tuple)
+	if !ok {
+		return fmt.Errorf("call arguments %T", args)
+	}
+
+	module := c.Module
+	switch module {
+	case "__builtin__":
+		module = "builtins"
+	case "copy_reg":
+		module = "copyreg"
+	}
+
+	switch module + "." + c.Name {
+	case "copyreg._reconstructor":
+		// Protocol 0 and 1 way of creating new style class instances: _reconstructor(cls, base, state).
+		if len(t) < 1 {
+			return errors.New("_reconstructor without arguments")
+		}
+		cls, ok := t[0].(Class)
+		if !ok {
+			return fmt.Errorf("_reconstructor of %T", t[0])
+		}
+		o := &Object{Class: cls}
+		// State is the value of base type, contents of list and dict subclasses.
+		if len(t) == 3 {
+			switch v := t[2].(type) {
+			case *List:
+				o.Items = v.Items
+			case *Dict:
+				o.Dict = v
+			}
+		}
+		d.push(o)
+		return nil
+	case "_codecs.encode":
+		// Python 3 bytes pickled with protocol 2.
+		if len(t) == 2 && t[1] == any("latin1") {
+			if s, ok := t[0].(string); ok {
+				b := make(Bytes, 0, len(s))
+				for _, r := range s {
+					b = append(b, byte(r))
+				}
+				d.push(b)
+				return nil
+			}
+		}
+	case "builtins.bytes", "builtins.bytearray":
+		if len(t) == 0 {
+			d.push(Bytes{})
+			return nil
+		}
+	case "builtins.set", "builtins.frozenset":
+		l := &List{}
+		if len(t) == 1 {
+			if items, ok := t[0].(*List); ok {
+				l.Items = append(l.Items, items.Items...)
+			}
+		}
+		d.push(l)
+		return nil
+	case "collections.OrderedDict":
+		dict := &Dict{}
+		if len(t) == 1 {
+			if items, ok := t[0].(*List); ok {
+				for _, item := range items.Items {
+					kv, ok := item.(*List)
+					if ok && len(kv.Items) == 2 {
+						dict.set(kv.Items[0], kv.Items[1])
+					}
+				}
+			}
+		}
+		d.push(dict)
+		return nil
+	}
+
+	d.push(&Object{Class: c, Args: t})
+	return nil
+}
+
+func (d *decoder) newObject(class, args any) error {
+	c, ok := class.(Class)
+	if !ok {
+		return fmt.Errorf("new object of %T", class)
+	}
+	t, ok := args.(Tuple)
+	if !ok {
+		return fmt.Errorf("new object arguments %T", args)
+	}
+	d.push(&Object{Class: c, Args: t})
+
+	return nil
+}
+
+// equal compares dict keys, unhashable values are never equal.
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case nil, bool, int64, float64, string, Class:
+		return a == b
+	case Tuple:
+		b, ok := b.(Tuple)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case Bytes:
+		b, ok := b.(Bytes)
+		return ok && bytes.Equal(a, b)
+	}
+
+	return false
+}
+
+// unquote decodes python 2 repr of a str, bytes are kept as is.
+func unquote(s string) (string, error) {
+	if len(s) < 2 || (s[0] != '\'' && s[0] != '"') || s[len(s)-1] != s[0] {
+		return "", fmt.Errorf("bad string %q", s)
+	}
+	s = s[1 : len(s)-1]
+
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b = append(b, s[i])
+			continue
+		}
+
+		i++
+		switch c := s[i]; c {
+		case 'n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case 't':
+			b = append(b, '\t')
+		case 'x':
+			if i+2 >= len(s) {
+				return "", fmt.Errorf("bad escape in %q", s)
+			}
+			v, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", err
+			}
+			b = append(b, byte(v))
+			i += 2
+		default:
+			b = append(b, c)
+		}
+	}
+
+	return string(b), nil
+}
+
+// decodeRawUnicodeEscape decodes \uXXXX and \UXXXXXXXX escapes, other bytes are latin-1 characters.
+func decodeRawUnicodeEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && (s[i+1] == 'u' || s[i+1] == 'U') {
+			n := 4
+			if s[i+1] == 'U' {
+				n = 8
+			}
+			if i+2+n <= len(s) {
+				if v, err := strconv.ParseUint(s[i+2:i+2+n], 16, 32); err == nil && utf8.ValidRune(rune(v)) {
+					b.WriteRune(rune(v))
+					i += 1 + n
+					continue
+				}
+			}
+		}
+		b.WriteRune(rune(s[i]))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,163 @@
+package pickle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestProtocols decodes the same value pickled by python with protocols 0-5, see testdata/gen.py.
+func TestProtocols(t *testing.T) {
+	for proto := range 6 {
+		t.Run(fmt.Sprint(proto), func(t *testing.T) {
+			f, err := os.Open(fmt.Sprintf("testdata/proto%d.pickle", proto))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			v, err := Load(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, ok := v.(*Dict)
+			if !ok {
+				t.Fatalf("got %T, want *Dict", v)
+			}
+			get := func(key string) any {
+				v, ok := d.Get(key)
+				if !ok {
+					t.Errorf("no key %q", key)
+				}
+				return v
+			}
+
+			big70 := new(big.Int).Lsh(big.NewInt(1), 70)
+			for key, want := range map[string]any{
+				"int":   int64(7),
+				"neg":   int64(-300),
+				"big":   big70,
+				"float": 1.5,
+				"str":   "héllo",
+				"bytes": Bytes{0xff, 0},
+				"tuple": Tuple{int64(1), "a"},
+				"none":  nil,
+				"true":  true,
+				"set":   &List{Items: []any{int64(3)}},
+			} {
+				if got := get(key); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: got %#v, want %#v", key, got, want)
+				}
+			}
+
+			// Instance is created by NEWOBJ or _reconstructor and its attributes are set by BUILD.
+			obj, ok := get("obj").(*Object)
+			if !ok {
+				t.Fatalf("obj: got %T, want *Object", get("obj"))
+			}
+			if obj.Class != (Class{Module: "m", Name: "C"}) {
+				t.Errorf("obj: got class %v", obj.Class)
+			}
+			if obj.Get("name") != "say" {
+				t.Errorf("obj.name: got %#v", obj.Get("name"))
+			}
+
+			// Memoized list is the same value in both places.
+			shared, ok := get("shared").(*List)
+			if !ok || !reflect.DeepEqual(shared.Items, []any{int64(1), int64(2)}) {
+				t.Errorf("shared: got %#v", get("shared"))
+			}
+			if obj.Get("shared") != any(shared) {
+				t.Errorf("obj.shared is not the memoized list")
+			}
+
+			// Items of list and dict subclasses are appended to the object.
+			items, ok := get("items").(*Object)
+			if !ok || items.Class.Name != "L" || !reflect.DeepEqual(items.Items, []any{int64(1)}) {
+				t.Errorf("items: got %#v", get("items"))
+			}
+			dict, ok := get("dict").(*Object)
+			if !ok || dict.Class.Name != "D" || dict.Dict == nil || !reflect.DeepEqual(dict.Dict.Keys, []any{"a"}) {
+				t.Errorf("dict: got %#v", get("dict"))
+			}
+		})
+	}
+}
+
+func TestOpcodes(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  any
+	}{
+		{"mark tuple", "(K\x01K\x02t.", Tuple{int64(1), int64(2)}},
+		{"nested marks", "(K\x01(K\x02ll.", &List{Items: []any{int64(1), &List{Items: []any{int64(2)}}}}},
+		{"pop mark", "K\x01(K\x02K\x031.", int64(1)},
+		{"dup", "K\x012\x86.", Tuple{int64(1), int64(1)}},
+		{"memo", "]q\x00h\x00\x86.", Tuple{&List{}, &List{}}},
+		{"memoize", "\x80\x04]\x94h\x00\x86.", Tuple{&List{}, &List{}}},
+		{"text memo", "]p0\ng0\n\x86.", Tuple{&List{}, &List{}}},
+		{"long1", "\x8a\x02\x00\x80.", int64(-32768)},
+		{"int bool", "I01\n.", true},
+		{"long text", "L123456789012345678901234567890L\n.", func() any { v, _ := new(big.Int).SetString("123456789012345678901234567890", 10); return v }()},
+		{"string escapes", "S'a\\nb\\x00'\n.", "a\nb\x00"},
+		{"unicode escapes", "V\\u00e9\\U0001f600\n.", "é😀"},
+		{"global", "cm\nC\n.", Class{Module: "m", Name: "C"}},
+		{"stack global", "\x8c\x01m\x8c\x01C\x93.", Class{Module: "m", Name: "C"}},
+		{"reduce", "cm\nC\nK\x01\x85R.", &Object{Class: Class{"m", "C"}, Args: Tuple{int64(1)}}},
+		{"newobj", "cm\nC\n)\x81}b.", &Object{Class: Class{"m", "C"}, Args: Tuple{}, State: &Dict{}}},
+		{"newobj ex", "cm\nC\n)}\x92.", &Object{Class: Class{"m", "C"}, Args: Tuple{}}},
+		{"inst", "(K\x01im\nC\n.", &Object{Class: Class{"m", "C"}, Args: Tuple{int64(1)}}},
+		{"obj", "(cm\nC\nK\x01o.", &Object{Class: Class{"m", "C"}, Args: Tuple{int64(1)}}},
+		{"build slots", "cm\nC\n)\x81N}X\x01\x00\x00\x00xK\x01s\x86b.", &Object{Class: Class{"m", "C"}, Args: Tuple{}, State: Tuple{nil, &Dict{Keys: []any{"x"}, Values: []any{int64(1)}}}}},
+		{"dict setitem", "}K\x01K\x02s.", &Dict{Keys: []any{int64(1)}, Values: []any{int64(2)}}},
+		{"frame", "\x80\x04\x95\x02\x00\x00\x00\x00\x00\x00\x00K\x01.", int64(1)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := Load(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(v, tc.want) {
+				t.Errorf("got %#v, want %#v", v, tc.want)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+	}{
+		{"mark items popped", "N(0t."},
+		{"no mark", "K\x01t."},
+		{"empty stack", "0."},
+		{"unknown memo", "h\x05."},
+		{"build of non object", "K\x01}b."},
+		{"append to non list", "K\x01K\x02a."},
+		{"odd dict items", "(K\x01d."},
+		{"unsupported opcode", "\xff."},
+		{"truncated", "X\x05\x00\x00\x00ab"},
+		{"no stop", "K\x01"},
+		// Length is checked against data actually read, not allocated upfront.
+		{"huge length", "\x8d\x00\x00\x00\x30\x00\x00\x00\x00abc"},
+		{"negative length", "\x8d\xff\xff\xff\xff\xff\xff\xff\xffabc"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Load(strings.NewReader(tc.input)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	_, err := Load(strings.NewReader("X\x05\x00\x00\x00ab"))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("truncated: got %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,50 @@
+# Generates protoN.pickle files used by pickle tests: python3 gen.py
+import pickle
+import sys
+import types
+
+m = types.ModuleType("m")
+sys.modules["m"] = m
+
+
+class C:
+    pass
+
+
+class L(list):
+    pass
+
+
+class D(dict):
+    pass
+
+
+for cls in (C, L, D):
+    cls.__module__ = "m"
+    setattr(m, cls.__name__, cls)
+
+shared = [1, 2]
+c = C()
+c.name = "say"
+c.shared = shared
+
+value = {
+    "int": 7,
+    "neg": -300,
+    "big": 2**70,
+    "float": 1.5,
+    "str": "héllo",
+    "bytes": b"\xff\x00",
+    "tuple": (1, "a"),
+    "none": None,
+    "true": True,
+    "obj": c,
+    "shared": shared,
+    "set": {3},
+    "items": L([1]),
+    "dict": D(a=1),
+}
+
+for proto in range(6):
+    with open("proto%d.pickle" % proto, "wb") as f:
+        pickle.dump(value, f, proto)
@@ -0,0 +1,676 @@
+package rpyc
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kaey/gamearc/internal/pickle"
+)
+
+// block prints a list of statements. Some statements are printed together with the following ones,
+// such as call and its return label, so statements are printed by index.
+func (p *printer) block(stmts []*pickle.Object) {
+	if len(stmts) == 0 {
+		p.line("pass")
+		return
+	}
+
+	// Separate top level statements, except runs of single line statements of the same kind.
+	prevName, prevLines := "", 0
+	for i := 0; i < len(stmts); {
+		name := stmts[i].Class.Name
+		if p.indent == 0 && i > 0 && (name != prevName || prevLines > 1) {
+			p.blank()
+		}
+
+		start := len(p.lines)
+		i += p.stmt(stmts, i)
+		prevName, prevLines = name, len(p.lines)-start
+	}
+}
+
+// stmt prints statement stmts[i] and returns the number of consumed statements.
+func (p *printer) stmt(stmts []*pickle.Object, i int) int {
+	s := stmts[i]
+	next := func(j int) *pickle.Object {
+		if i+j < len(stmts) {
+			return stmts[i+j]
+		}
+		return nil
+	}
+
+	switch s.Class.Name {
+	case "Label":
+		p.label(s)
+	case "Say", "TranslateSay":
+		// Ren'Py 8.2 and later merge translate block of a single say statement into the statement.
+		if s.Class.Name == "TranslateSay" && get(s, "language") != nil {
+			p.line("translate %s %s:", str(get(s, "language")), str(get(s, "identifier")))
+			p.nested(func() { p.say(s, false) })
+			break
+		}
+		// Say without interaction followed by menu is the menu caption.
+		if n := next(1); n != nil && n.Class.Name == "Menu" && get(s, "interact") == false {
+			p.menu(n, s)
+			return 2
+		}
+		p.say(s, false)
+	case "Menu":
+		p.menu(s, nil)
+	case "Init":
+		p.init(s)
+	case "Python", "EarlyPython":
+		p.python(s, "")
+	case "Define", "Default":
+		p.define(s)
+	case "Jump":
+		if get(s, "expression") == true {
+			p.line("jump expression %s", str(get(s, "target")))
+		} else {
+			p.line("jump %s", str(get(s, "target")))
+		}
+	case "Call":
+		line := "call " + str(get(s, "label"))
+		if get(s, "expression") == true {
+			line = "call expression " + str(get(s, "label"))
+			if get(s, "arguments") != nil {
+				line += " pass"
+			}
+		}
+		line += arguments(get(s, "arguments"))
+		// Return label of call is a separate empty label following it.
+		if n := next(1); n != nil && n.Class.Name == "Label" && len(list(get(n, "block"))) == 0 {
+			p.line("%s from %s", line, str(get(n, "name")))
+			return 2
+		}
+		p.line("%s", line)
+	case "Return":
+		if e := get(s, "expression"); e != nil {
+			p.line("return %s", str(e))
+		} else {
+			p.line("return")
+		}
+	case "If":
+		p.conditions("if", "elif", list(get(s, "entries")), func(block any) {
+			p.block(objects(block))
+		})
+	case "While":
+		p.line("while %s:", str(get(s, "condition")))
+		p.nested(func() { p.block(objects(get(s, "block"))) })
+	case "Pass":
+		p.line("pass")
+	case "Scene":
+		line := "scene"
+		if im := get(s, "imspec"); im != nil {
+			line += " " + imspec(im)
+		} else if layer := get(s, "layer"); layer != nil {
+			line += " onlayer " + str(layer)
+		}
+		p.withATL(line, get(s, "atl"))
+	case "Show":
+		p.withATL("show "+imspec(get(s, "imspec")), get(s, "atl"))
+	case "Hide":
+		p.line("hide %s", imspec(get(s, "imspec")))
+	case "ShowLayer":
+		p.withATL("show layer "+str(get(s, "layer"))+atList(get(s, "at_list")), get(s, "atl"))
+	case "Camera":
+		line := "camera"
+		if layer := str(get(s, "layer")); layer != "master" {
+			line += " " + layer
+		}
+		p.withATL(line+atList(get(s, "at_list")), get(s, "atl"))
+	case "With":
+		// "show x with t" is compiled to: with None (paired with t), show x, with t.
+		if paired := get(s, "paired"); paired != nil && next(1) != nil {
+			start := len(p.lines)
+			n := p.stmt(stmts, i+1)
+			p.appendWith(start, str(paired))
+			if w := next(1 + n); w != nil && w.Class.Name == "With" && str(get(w, "expr")) == str(paired) {
+				n++
+			}
+			return 1 + n
+		}
+		p.line("with %s", str(get(s, "expr")))
+	case "UserStatement":
+		p.line("%s", str(get(s, "line")))
+		p.nested(func() { p.rawBlock(list(get(s, "block"))) })
+	case "Image":
+		line := "image " + strings.Join(strs(get(s, "imgname")), " ")
+		if code := get(s, "code"); code != nil {
+			p.line("%s = %s", line, source(code))
+		} else {
+			p.withATL(line, get(s, "atl"))
+		}
+	case "Transform":
+		line := "transform " + storeName(get(s, "store")) + str(get(s, "varname")) + parameters(get(s, "parameters"))
+		p.withATL(line, get(s, "atl"))
+	case "Style":
+		p.style(s)
+	case "Screen":
+		p.screen(get(s, "screen"))
+	case "Translate":
+		// Dialogue of the game itself is wrapped in translate blocks without language.
+		if lang := get(s, "language"); lang != nil {
+			p.line("translate %s %s:", str(lang), str(get(s, "identifier")))
+			p.nested(func() { p.block(objects(get(s, "block"))) })
+		} else {
+			p.block(objects(get(s, "block")))
+		}
+	case "EndTranslate":
+	case "TranslateString":
+		// Consecutive strings of the same language are printed in a single block.
+		lang := get(s, "language")
+		p.line("translate %s strings:", strOr(lang, "None"))
+		n := 0
+		p.nested(func() {
+			for ; next(n) != nil && next(n).Class.Name == "TranslateString" && get(next(n), "language") == lang; n++ {
+				if n > 0 {
+					p.blank()
+				}
+				p.line("old %s", Quote(str(get(next(n), "old"))))
+				p.line("new %s", Quote(str(get(next(n), "new"))))
+			}
+		})
+		return n
+	case "TranslatePython", "TranslateBlock", "TranslateEarlyBlock":
+		lang := strOr(get(s, "language"), "None")
+		if code := get(s, "code"); code != nil {
+			p.line("translate %s python:", lang)
+			p.nested(func() { p.code(source(code)) })
+			break
+		}
+		for _, c := range objects(get(s, "block")) {
+			p.prefix = "translate " + lang + " "
+			p.stmt([]*pickle.Object{c}, 0)
+		}
+	default:
+		p.line("# unsupported statement %s", s.Class)
+	}
+
+	return 1
+}
+
+func (p *printer) label(s *pickle.Object) {
+	line := "label " + str(get(s, "name")) + parameters(get(s, "parameters"))
+	if get(s, "hide") == true {
+		line += " hide"
+	}
+	p.line("%s:", line)
+
+	// Labels without a block are followed by their statements at the same level.
+	if block := objects(get(s, "block")); len(block) > 0 {
+		p.nested(func() { p.block(block) })
+	}
+}
+
+// say prints say statement, caption of a menu does not interact by itself, so nointeract is omitted.
+func (p *printer) say(s *pickle.Object, caption bool) {
+	p.line("%s", sayCode(s, !caption))
+}
+
+// Code returns Ren'Py code of say or user statement, the same as Ren'Py computes identifiers
+// of translate blocks from. Empty string is returned for other statements.
+func Code(s *pickle.Object) string {
+	switch s.Class.Name {
+	case "Say", "TranslateSay":
+		return sayCode(s, true)
+	case "UserStatement":
+		return str(get(s, "line"))
+	}
+
+	return ""
+}
+
+// sayCode returns code of say statement, nointeract is omitted for menu captions.
+func sayCode(s *pickle.Object, nointeract bool) string {
+	var words []string
+	if who := str(get(s, "who")); who != "" {
+		words = append(words, who)
+	}
+	words = append(words, strs(get(s, "attributes"))...)
+	if temp := strs(get(s, "temporary_attributes")); len(temp) > 0 {
+		words = append(words, "@")
+		words = append(words, temp...)
+	}
+	words = append(words, Quote(str(get(s, "what"))))
+	if get(s, "interact") == false && nointeract {
+		words = append(words, "nointeract")
+	}
+	if id := get(s, "identifier"); id != nil && get(s, "explicit_identifier") == true {
+		words = append(words, "id", str(id))
+	}
+	if args := arguments(get(s, "arguments")); args != "" {
+		words = append(words, args)
+	}
+	if w := get(s, "with_"); w != nil {
+		words = append(words, "with", str(w))
+	}
+
+	return strings.Join(words, " ")
+}
+
+func (p *printer) menu(s, caption *pickle.Object) {
+	p.line("menu%s:", arguments(get(s, "arguments")))
+	p.nested(func() {
+		if caption != nil {
+			p.say(caption, true)
+		}
+		if set := get(s, "set"); set != nil {
+			p.line("set %s", str(set))
+		}
+
+		itemArgs := list(get(s, "item_arguments"))
+		for i, item := range list(get(s, "items")) {
+			t := list(item)
+			if len(t) != 3 {
+				continue
+			}
+			label, cond, block := t[0], t[1], t[2]
+			if block == nil {
+				p.line("%s", Quote(str(label)))
+				continue
+			}
+
+			line := Quote(str(label))
+			if i < len(itemArgs) {
+				line += arguments(itemArgs[i])
+			}
+			if c := str(cond); c != "True" {
+				line += " if " + c
+			}
+			p.line("%s:", line)
+			p.nested(func() { p.block(objects(block)) })
+		}
+	})
+}
+
+// defaultPriority is init priority of statements, which are wrapped in Init by parser.
+var defaultPriority = map[string]int64{
+	"Define":    0,
+	"Default":   0,
+	"Transform": 0,
+	"Style":     0,
+	"Image":     500,
+	"Screen":    -500,
+}
+
+func (p *printer) init(s *pickle.Object) {
+	priority, _ := get(s, "priority").(int64)
+	block := objects(get(s, "block"))
+
+	if len(block) == 1 {
+		child := block[0]
+		if prio, ok := defaultPriority[child.Class.Name]; ok && prio == priority {
+			p.stmt(block, 0)
+			return
+		}
+		if child.Class.Name == "Python" {
+			p.python(child, initPrefix(priority))
+			return
+		}
+	}
+
+	p.line("%s:", strings.TrimSpace(initPrefix(priority)))
+	p.nested(func() { p.block(block) })
+}
+
+func initPrefix(priority int64) string {
+	if priority == 0 {
+		return "init "
+	}
+	return fmt.Sprintf("init %d ", priority)
+}
+
+// python prints python block or a single line $ statement.
+func (p *printer) python(s *pickle.Object, prefix string) {
+	src := strings.Trim(source(get(s, "code")), "\n")
+
+	line := prefix + "python"
+	if s.Class.Name == "EarlyPython" {
+		line += " early"
+	}
+	if get(s, "hide") == true {
+		line += " hide"
+	}
+	if store := storeName(get(s, "store")); store != "" {
+		line += " in " + strings.TrimSuffix(store, ".")
+	}
+
+	if line == "python" && !strings.Contains(src, "\n") {
+		p.line("$ %s", src)
+		return
+	}
+
+	p.line("%s:", line)
+	p.nested(func() { p.code(src) })
+}
+
+// code prints python source lines, they keep their own indentation.
+func (p *printer) code(src string) {
+	for _, l := range strings.Split(strings.Trim(src, "\n"), "\n") {
+		if strings.TrimSpace(l) == "" {
+			p.lines = append(p.lines, "")
+			continue
+		}
+		p.line("%s", l)
+	}
+}
+
+func (p *printer) define(s *pickle.Object) {
+	name := storeName(get(s, "store")) + str(get(s, "varname"))
+	if index := get(s, "index"); index != nil {
+		name += "[" + source(index) + "]"
+	}
+	op := strOr(get(s, "operator"), "=")
+	kw := "define"
+	if s.Class.Name == "Default" {
+		kw = "default"
+	}
+
+	p.line("%s %s %s %s", kw, name, op, source(get(s, "code")))
+}
+
+func (p *printer) style(s *pickle.Object) {
+	line := "style " + str(get(s, "style_name"))
+	if parent := get(s, "parent"); parent != nil {
+		line += " is " + str(parent)
+	}
+	if get(s, "clear") == true {
+		line += " clear"
+	}
+	if take := get(s, "take"); take != nil {
+		line += " take " + str(take)
+	}
+	for _, d := range list(get(s, "delattr")) {
+		line += " del " + str(d)
+	}
+	if variant := get(s, "variant"); variant != nil {
+		line += " variant " + str(variant)
+	}
+
+	props, _ := get(s, "properties").(*pickle.Dict)
+	if props == nil || len(props.Keys) == 0 {
+		p.line("%s", line)
+		return
+	}
+
+	p.line("%s:", line)
+	p.nested(func() {
+		for i, k := range props.Keys {
+			p.line("%s %s", str(k), str(props.Values[i]))
+		}
+	})
+}
+
+// conditions prints if/elif/else chain, the last entry with True condition is else.
+func (p *printer) conditions(first, other string, entries []any, block func(any)) {
+	for i, e := range entries {
+		t := list(e)
+		if len(t) != 2 {
+			continue
+		}
+
+		cond := str(t[0])
+		switch {
+		case i == 0:
+			p.line("%s %s:", first, cond)
+		case i == len(entries)-1 && cond == "True":
+			p.line("else:")
+		default:
+			p.line("%s %s:", other, cond)
+		}
+		p.nested(func() { block(t[1]) })
+	}
+}
+
+// withATL prints line, followed by ATL block if there is one.
+func (p *printer) withATL(line string, atl any) {
+	if atl == nil {
+		p.line("%s", line)
+		return
+	}
+
+	p.line("%s:", line)
+	p.nested(func() { p.atl(atl) })
+}
+
+// appendWith adds with clause to the statement printed starting at line start.
+func (p *printer) appendWith(start int, transition string) {
+	if start >= len(p.lines) {
+		return
+	}
+
+	// Statement with a block, with goes before the colon.
+	if l, ok := strings.CutSuffix(p.lines[start], ":"); ok && start < len(p.lines)-1 {
+		p.lines[start] = l + " with " + transition + ":"
+	} else {
+		p.lines[start] += " with " + transition
+	}
+}
+
+// rawBlock prints unparsed block of user statement, which consists of (filename, line number, text, block) tuples.
+func (p *printer) rawBlock(block []any) {
+	for _, b := range block {
+		t := list(b)
+		if len(t) != 4 {
+			continue
+		}
+		p.line("%s", str(t[2]))
+		p.nested(func() { p.rawBlock(list(t[3])) })
+	}
+}
+
+// imspec prints image specification, it is a tuple of
+// (name, at_list, layer), (name, expression, tag, at_list, layer, zorder) or the same with behind list.
+func imspec(v any) string {
+	t := list(v)
+	var name, expression, tag, layer, zorder any
+	var at, behind any
+	switch len(t) {
+	case 3:
+		name, at, layer = t[0], t[1], t[2]
+	case 6, 7:
+		name, expression, tag, at, layer, zorder = t[0], t[1], t[2], t[3], t[4], t[5]
+		if len(t) == 7 {
+			behind = t[6]
+		}
+	default:
+		return ""
+	}
+
+	line := strings.Join(strs(name), " ")
+	if expression != nil {
+		line = "expression " + str(expression)
+	}
+	if tag != nil {
+		line += " as " + str(tag)
+	}
+	line += atList(at)
+	if layer != nil {
+		line += " onlayer " + str(layer)
+	}
+	if zorder != nil {
+		line += " zorder " + str(zorder)
+	}
+	if b := strs(behind); len(b) > 0 {
+		line += " behind " + strings.Join(b, ", ")
+	}
+
+	return line
+}
+
+func atList(v any) string {
+	if at := strs(v); len(at) > 0 {
+		return " at " + strings.Join(at, ", ")
+	}
+	return ""
+}
+
+// parameters prints ParameterInfo or Signature of newer versions, empty string if there are none.
+func parameters(v any) string {
+	o, ok := v.(*pickle.Object)
+	if !ok {
+		return ""
+	}
+
+	var params []string
+	if o.Class.Name == "Signature" {
+		// Parameter kinds are the same as in inspect module.
+		const (
+			positionalOnly = iota
+			positionalOrKeyword
+			varPositional
+			keywordOnly
+			varKeyword
+		)
+
+		d, _ := get(o, "parameters").(*pickle.Dict)
+		if d == nil {
+			return "()"
+		}
+		star, slash := false, false
+		for i, k := range d.Values {
+			kind, _ := get(k, "kind").(int64)
+			name := str(d.Keys[i])
+			if slash && kind != positionalOnly {
+				params = append(params, "/")
+				slash = false
+			}
+			if kind == keywordOnly && !star {
+				params = append(params, "*")
+				star = true
+			}
+			switch kind {
+			case positionalOnly:
+				slash = true
+			case varPositional:
+				name = "*" + name
+				star = true
+			case varKeyword:
+				name = "**" + name
+			}
+			if def := get(k, "default"); def != nil {
+				name += "=" + str(def)
+			}
+			params = append(params, name)
+		}
+		if slash {
+			params = append(params, "/")
+		}
+
+		return "(" + strings.Join(params, ", ") + ")"
+	}
+
+	positional := strs(get(o, "positional"))
+	extrapos := get(o, "extrapos")
+	star := false
+	for _, param := range list(get(o, "parameters")) {
+		t := list(param)
+		if len(t) != 2 {
+			continue
+		}
+		name := str(t[0])
+		if !star && !slices.Contains(positional, name) {
+			if extrapos != nil {
+				params = append(params, "*"+str(extrapos))
+			} else {
+				params = append(params, "*")
+			}
+			star = true
+		}
+		if t[1] != nil {
+			name += "=" + str(t[1])
+		}
+		params = append(params, name)
+	}
+	if !star && extrapos != nil {
+		params = append(params, "*"+str(extrapos))
+	}
+	if extrakw := get(o, "extrakw"); extrakw != nil {
+		params = append(params, "**"+str(extrakw))
+	}
+
+	return "(" + strings.Join(params, ", ") + ")"
+}
+
+// arguments prints ArgumentInfo, empty string if there are none.
+func arguments(v any) string {
+	o, ok := v.(*pickle.Object)
+	if !ok {
+		return ""
+	}
+
+	starred := ints(get(o, "starred_indexes"))
+	doubleStarred := ints(get(o, "doublestarred_indexes"))
+	var args []string
+	for i, arg := range list(get(o, "arguments")) {
+		t := list(arg)
+		if len(t) != 2 {
+			continue
+		}
+		a := str(t[1])
+		switch {
+		case slices.Contains(starred, int64(i)):
+			a = "*" + a
+		case slices.Contains(doubleStarred, int64(i)):
+			a = "**" + a
+		case t[0] != nil:
+			a = str(t[0]) + "=" + a
+		}
+		args = append(args, a)
+	}
+	if extrapos := get(o, "extrapos"); extrapos != nil {
+		args = append(args, "*"+str(extrapos))
+	}
+	if extrakw := get(o, "extrakw"); extrakw != nil {
+		args = append(args, "**"+str(extrakw))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+// storeName returns store prefix of a variable, empty for the default store.
+func storeName(v any) string {
+	store := str(v)
+	if store == "" || store == "store" {
+		return ""
+	}
+	return strings.TrimPrefix(store, "store.") + "."
+}
+
+func strOr(v any, def string) string {
+	if v == nil {
+		return def
+	}
+	return str(v)
+}
+
+func strs(v any) []string {
+	var res []string
+	for _, s := range list(v) {
+		res = append(res, str(s))
+	}
+	return res
+}
+
+func ints(v any) []int64 {
+	var res []int64
+	for _, i := range list(v) {
+		if i, ok := i.(int64); ok {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// objects returns objects in a list, skipping anything else.
+func objects(v any) []*pickle.Object {
+	var res []*pickle.Object
+	for _, s := range list(v) {
+		if o, ok := s.(*pickle.Object); ok {
+			res = append(res, o)
+		}
+	}
+	return res
+}
@@ -0,0 +1,136 @@
+package rpyc
+
+import (
+	"strings"
+
+	"github.com/kaey/gamearc/internal/pickle"
+)
+
+// atl prints statements of ATL RawBlock.
+func (p *printer) atl(block any) {
+	if get(block, "animation") == true {
+		p.line("animation")
+	}
+
+	stmts := objects(get(block, "statements"))
+	if len(stmts) == 0 {
+		p.line("pass")
+		return
+	}
+	for _, s := range stmts {
+		p.atlStmt(s)
+	}
+}
+
+func (p *printer) atlStmt(s *pickle.Object) {
+	switch s.Class.Name {
+	case "RawMultipurpose":
+		p.line("%s", multipurpose(s))
+	case "RawRepeat":
+		if n := get(s, "repeats"); n != nil {
+			p.line("repeat %s", str(n))
+		} else {
+			p.line("repeat")
+		}
+	case "RawBlock":
+		p.line("block:")
+		p.nested(func() { p.atl(s) })
+	case "RawParallel":
+		for _, b := range list(get(s, "blocks")) {
+			p.line("parallel:")
+			p.nested(func() { p.atl(b) })
+		}
+	case "RawChoice":
+		for _, c := range list(get(s, "choices")) {
+			t := list(c)
+			if len(t) != 2 {
+				continue
+			}
+			if chance := str(t[0]); chance != "1.0" {
+				p.line("choice %s:", chance)
+			} else {
+				p.line("choice:")
+			}
+			p.nested(func() { p.atl(t[1]) })
+		}
+	case "RawOn":
+		handlers, _ := get(s, "handlers").(*pickle.Dict)
+		if handlers == nil {
+			break
+		}
+		for i, name := range handlers.Keys {
+			p.line("on %s:", str(name))
+			p.nested(func() { p.atl(handlers.Values[i]) })
+		}
+	case "RawChild":
+		for _, c := range list(get(s, "children")) {
+			p.line("contains:")
+			p.nested(func() { p.atl(c) })
+		}
+	case "RawContainsExpr":
+		p.line("contains %s", str(get(s, "expression")))
+	case "RawTime":
+		p.line("time %s", str(get(s, "time")))
+	case "RawFunction":
+		p.line("function %s", str(get(s, "expr")))
+	case "RawEvent":
+		p.line("event %s", str(get(s, "name")))
+	default:
+		p.line("# unsupported ATL statement %s", s.Class)
+	}
+}
+
+// multipurpose prints interpolation, pause and property statement.
+func multipurpose(s *pickle.Object) string {
+	var words []string
+	if warp := get(s, "warp_function"); warp != nil {
+		words = append(words, "warp", str(warp), str(get(s, "duration")))
+	} else if warper := get(s, "warper"); warper != nil {
+		words = append(words, str(warper), str(get(s, "duration")))
+	} else if d := str(get(s, "duration")); d != "" && d != "0" {
+		words = append(words, "pause", d)
+	}
+
+	if r := get(s, "revolution"); r != nil {
+		words = append(words, str(r))
+	}
+	if c := str(get(s, "circles")); c != "" && c != "0" {
+		words = append(words, "circles", c)
+	}
+
+	for _, prop := range list(get(s, "properties")) {
+		if t := list(prop); len(t) == 2 {
+			words = append(words, str(t[0]), str(t[1]))
+		}
+	}
+	for _, spline := range list(get(s, "splines")) {
+		t := list(spline)
+		if len(t) != 2 {
+			continue
+		}
+		exprs := strs(t[1])
+		if len(exprs) == 0 {
+			continue
+		}
+		words = append(words, str(t[0]), exprs[0])
+		for _, k := range exprs[1:] {
+			words = append(words, "knot", k)
+		}
+	}
+	for _, expr := range list(get(s, "expressions")) {
+		t := list(expr)
+		if len(t) != 2 {
+			continue
+		}
+		words = append(words, str(t[0]))
+		if t[1] != nil {
+			words = append(words, "with", str(t[1]))
+		}
+	}
+
+	if len(words) == 0 {
+		return "pass"
+	}
+
+	return strings.Join(words, " ")
+}
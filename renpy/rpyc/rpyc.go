@@ -0,0 +1,217 @@
+// Package rpyc decompiles Ren'Py compiled scripts (.rpyc) to source (.rpy).
+//
+// Script is a zlib compressed pickle of Ren'Py AST, which is unpickled into generic objects
+// without Ren'Py classes and printed back as source. Statements which can not be printed
+// are replaced with comments, so the output is meant for reading and translation,
+// it is not guaranteed to compile into the same script.
+package rpyc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/kaey/gamearc/internal/pickle"
+)
+
+// Load returns top level statements of compiled script.
+//
+// Old scripts are just a compressed pickle, newer ones start with "RENPY RPC2" followed by
+// a table of slots: uint32 slot number, offset and length, terminated by zero slot.
+// Slot 1 contains a pickle of (data, statements).
+func Load(data []byte) ([]*pickle.Object, error) {
+	if bytes.HasPrefix(data, []byte("RENPY RPC2")) {
+		slot, err := findSlot(data, 1)
+		if err != nil {
+			return nil, err
+		}
+		data = slot
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompress: %w", err)
+	}
+	defer zr.Close()
+
+	v, err := pickle.Load(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	if t, ok := v.(pickle.Tuple); ok && len(t) == 2 {
+		v = t[1]
+	}
+	var stmts []*pickle.Object
+	for _, s := range list(v) {
+		o, ok := s.(*pickle.Object)
+		if !ok {
+			return nil, fmt.Errorf("expected statement, got %T", s)
+		}
+		stmts = append(stmts, o)
+	}
+
+	return stmts, nil
+}
+
+func findSlot(data []byte, n uint32) ([]byte, error) {
+	for pos := 10; pos+12 <= len(data); pos += 12 {
+		slot := binary.LittleEndian.Uint32(data[pos:])
+		start := binary.LittleEndian.Uint32(data[pos+4:])
+		length := binary.LittleEndian.Uint32(data[pos+8:])
+		if slot == 0 {
+			break
+		}
+		if slot != n {
+			continue
+		}
+		if uint64(start)+uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("slot %d beyond end of file", n)
+		}
+
+		return data[start : start+length], nil
+	}
+
+	return nil, fmt.Errorf("slot %d not found", n)
+}
+
+// Decompile writes source of compiled script data to w.
+func Decompile(w io.Writer, data []byte) error {
+	stmts, err := Load(data)
+	if err != nil {
+		return err
+	}
+
+	p := new(printer)
+	p.block(stmts)
+	_, err = io.WriteString(w, p.String())
+
+	return err
+}
+
+// printer collects indented lines, so that already printed line can be amended.
+type printer struct {
+	lines  []string
+	indent int
+
+	// prefix is prepended to the next printed line.
+	prefix string
+}
+
+func (p *printer) line(format string, args ...any) {
+	p.lines = append(p.lines, strings.Repeat("    ", p.indent)+p.prefix+fmt.Sprintf(format, args...))
+	p.prefix = ""
+}
+
+func (p *printer) blank() {
+	if len(p.lines) > 0 && p.lines[len(p.lines)-1] != "" {
+		p.lines = append(p.lines, "")
+	}
+}
+
+// nested prints lines printed by f with one more level of indentation.
+func (p *printer) nested(f func()) {
+	p.indent++
+	f()
+	p.indent--
+}
+
+func (p *printer) String() string {
+	return strings.Join(p.lines, "\n") + "\n"
+}
+
+// get returns attribute of an object, nil if v is not an object.
+func get(v any, name string) any {
+	if o, ok := v.(*pickle.Object); ok {
+		return o.Get(name)
+	}
+	return nil
+}
+
+// className returns name of object class without module.
+func className(v any) string {
+	if o, ok := v.(*pickle.Object); ok {
+		return o.Class.Name
+	}
+	return ""
+}
+
+// str converts strings and string subclasses, such as PyExpr, to string.
+func str(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case pickle.Bytes:
+		return string(v)
+	case int64:
+		return fmt.Sprint(v)
+	case *big.Int:
+		return v.String()
+	case float64:
+		return fmt.Sprint(v)
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	case *pickle.Object:
+		if len(v.Args) > 0 {
+			return str(v.Args[0])
+		}
+	}
+
+	return ""
+}
+
+// list returns items of a list or a tuple.
+func list(v any) []any {
+	switch v := v.(type) {
+	case *pickle.List:
+		return v.Items
+	case pickle.Tuple:
+		return v
+	case *pickle.Object:
+		return v.Items
+	}
+
+	return nil
+}
+
+// source returns python source of PyCode, which state is (version, source, location, mode[, py]).
+func source(v any) string {
+	o, ok := v.(*pickle.Object)
+	if !ok {
+		return str(v)
+	}
+	if t, ok := o.State.(pickle.Tuple); ok && len(t) > 1 {
+		return str(t[1])
+	}
+	return str(o.Get("source"))
+}
+
+// Quote returns Ren'Py string literal of s. Runs of spaces are escaped, because Ren'Py collapses them.
+func Quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == ' ' && i > 0 && s[i-1] == ' ':
+			b.WriteString(`\ `)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package rpyc
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// TestDecompile compares decompiled testdata/script.rpyc, generated by testdata/gen.py, with script.rpy.
+func TestDecompile(t *testing.T) {
+	want, err := os.ReadFile("testdata/script.rpy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"script.rpyc", "old.rpyc"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			if err := Decompile(&buf, data); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{`plain`, `"plain"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"a  b", `"a \ b"`},
+		{"line\nbreak", `"line\nbreak"`},
+		{`back\slash`, `"back\\slash"`},
+	} {
+		if got := Quote(tc.in); got != tc.want {
+			t.Errorf("Quote(%q): got %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package rpyc
+
+import (
+	"strings"
+
+	"github.com/kaey/gamearc/internal/pickle"
+)
+
+// screenProperties are properties of SLScreen with their default values, they are printed only when changed.
+var screenProperties = []struct{ name, def string }{
+	{"modal", "False"},
+	{"sensitive", "True"},
+	{"tag", ""},
+	{"zorder", "0"},
+	{"variant", "None"},
+	{"predict", "None"},
+	{"layer", "'screens'"},
+	{"roll_forward", "None"},
+}
+
+// displayables maps displayable functions of SLDisplayable to statement names.
+// Statements which share a function are distinguished by style, which is used when function is unknown.
+var displayables = map[string]string{
+	"Null":         "null",
+	"Text":         "text",
+	"Grid":         "grid",
+	"Side":         "side",
+	"Button":       "button",
+	"_textbutton":  "textbutton",
+	"_imagebutton": "imagebutton",
+	"_label":       "label",
+	"_key":         "key",
+	"Input":        "input",
+	"Timer":        "timer",
+	"Viewport":     "viewport",
+	"VPGrid":       "vpgrid",
+	"_imagemap":    "imagemap",
+	"_hotspot":     "hotspot",
+	"_hotbar":      "hotbar",
+	"sl2add":       "add",
+	"MouseArea":    "mousearea",
+	"OnEvent":      "on",
+	"Transform":    "transform",
+	"Drag":         "drag",
+	"DragGroup":    "draggroup",
+	"AreaPicker":   "areapicker",
+	"NearRect":     "nearrect",
+}
+
+// screen prints SLScreen of screen language 2.
+func (p *printer) screen(v any) {
+	s, ok := v.(*pickle.Object)
+	if !ok || s.Class.Name != "SLScreen" {
+		p.line("# unsupported screen %s, screen language 1 is not supported", str(get(v, "name")))
+		return
+	}
+
+	p.line("screen %s%s:", str(get(s, "name")), parameters(get(s, "parameters")))
+	p.nested(func() {
+		n := len(p.lines)
+		for _, prop := range screenProperties {
+			if v := get(s, prop.name); v != nil && str(v) != prop.def {
+				p.line("%s %s", prop.name, str(v))
+			}
+		}
+		p.slBlock(s)
+		if len(p.lines) == n {
+			p.line("pass")
+		}
+	})
+}
+
+// slBlock prints keywords and children of SLBlock and its subclasses.
+func (p *printer) slBlock(s any) {
+	for _, kw := range list(get(s, "keyword")) {
+		if t := list(kw); len(t) == 2 {
+			p.line("%s %s", str(t[0]), str(t[1]))
+		}
+	}
+	for _, c := range objects(get(s, "children")) {
+		p.slStmt(c)
+	}
+}
+
+// slChildren prints block of if, for or use, which is either SLBlock (or its subclass) or a list of children.
+func (p *printer) slChildren(s any) {
+	n := len(p.lines)
+	if _, ok := s.(*pickle.Object); ok {
+		p.slBlock(s)
+	} else {
+		for _, c := range objects(s) {
+			p.slStmt(c)
+		}
+	}
+	if len(p.lines) == n {
+		p.line("pass")
+	}
+}
+
+func (p *printer) slStmt(s *pickle.Object) {
+	switch s.Class.Name {
+	case "SLDisplayable":
+		p.slDisplayable(s)
+	case "SLIf", "SLShowIf":
+		kw := "if"
+		if s.Class.Name == "SLShowIf" {
+			kw = "showif"
+		}
+		p.conditions(kw, "elif", list(get(s, "entries")), p.slChildren)
+	case "SLFor":
+		variable := str(get(s, "variable"))
+		if index := get(s, "index_expression"); index != nil {
+			variable += " index " + str(index)
+		}
+		p.line("for %s in %s:", variable, str(get(s, "expression")))
+		p.nested(func() { p.slChildren(s) })
+	case "SLPython":
+		src := strings.Trim(source(get(s, "code")), "\n")
+		if !strings.Contains(src, "\n") {
+			p.line("$ %s", src)
+			break
+		}
+		p.line("python:")
+		p.nested(func() { p.code(src) })
+	case "SLPass":
+		p.line("pass")
+	case "SLDefault":
+		p.line("default %s = %s", str(get(s, "variable")), str(get(s, "expression")))
+	case "SLUse":
+		line := "use "
+		target := get(s, "target")
+		if _, ok := target.(string); ok {
+			line += str(target)
+		} else {
+			line += "expression " + str(target)
+			if get(s, "args") != nil {
+				line += " pass"
+			}
+		}
+		line += arguments(get(s, "args"))
+		if id := get(s, "id"); id != nil {
+			line += " id " + str(id)
+		}
+		if block := get(s, "block"); block != nil {
+			p.line("%s:", line)
+			p.nested(func() { p.slChildren(block) })
+			break
+		}
+		p.line("%s", line)
+	case "SLTransclude":
+		p.line("transclude")
+	case "SLBlock":
+		p.slBlock(s)
+	default:
+		p.line("# unsupported screen statement %s", s.Class)
+	}
+}
+
+func (p *printer) slDisplayable(s *pickle.Object) {
+	style := str(get(s, "style"))
+	name := style
+	if c, ok := get(s, "displayable").(pickle.Class); ok {
+		if n, ok := displayables[c.Name]; ok {
+			name = n
+		}
+	}
+	// Window and MultiBox are frame, window, vbox, hbox and fixed, Bar is bar and vbar.
+	if name == "" {
+		p.line("# unsupported displayable %v", get(s, "displayable"))
+		return
+	}
+
+	line := name
+	for _, pos := range strs(get(s, "positional")) {
+		line += " " + pos
+	}
+	if v := get(s, "variable"); v != nil {
+		line += " as " + str(v)
+	}
+
+	if len(list(get(s, "keyword"))) == 0 && len(list(get(s, "children"))) == 0 {
+		p.line("%s", line)
+		return
+	}
+
+	p.line("%s:", line)
+	p.nested(func() { p.slBlock(s) })
+}
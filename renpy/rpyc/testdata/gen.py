@@ -0,0 +1,162 @@
+# Generates script.rpyc and old.rpyc used by rpyc tests: python3 gen.py
+# Classes mimic pickled state of renpy.ast, renpy.atl and renpy.sl2 nodes.
+import sys, types, pickle, zlib, struct
+
+def mod(name):
+    m = types.ModuleType(name); sys.modules[name] = m; return m
+for n in ['renpy', 'renpy.ast', 'renpy.atl', 'renpy.sl2', 'renpy.sl2.slast', 'renpy.display', 'renpy.display.layout',
+          'renpy.text', 'renpy.text.text', 'renpy.ui', 'renpy.sl2.sldisplayables', 'renpy.object']:
+    mod(n)
+
+def cls(module, name, base=object, slots=None):
+    d = {'__module__': module}
+    if slots is not None:
+        d['__slots__'] = slots
+    c = type(name, (base,), d)
+    setattr(sys.modules[module], name, c)
+    return c
+
+class Node(object):
+    __slots__ = ['name', 'filename', 'linenumber', 'next', 'statement_start']
+    def __getstate__(self):
+        rv = {}
+        for k in type(self).__mro__:
+            for s in getattr(k, '__slots__', []):
+                if hasattr(self, s): rv[s] = getattr(self, s)
+        return (None, rv)
+Node.__module__ = 'renpy.ast'; renpy_ast = sys.modules['renpy.ast']; renpy_ast.Node = Node
+
+lineno = 0
+def node(clsname, **kw):
+    c = getattr(renpy_ast, clsname, None)
+    if c is None:
+        c = type(clsname, (Node,), {'__module__': 'renpy.ast', '__slots__': list(kw.keys())})
+        setattr(renpy_ast, clsname, c)
+    o = c.__new__(c)
+    global lineno
+    lineno += 1
+    o.filename, o.linenumber = 'game/script.rpy', lineno
+    for k, v in kw.items():
+        setattr(o, k, v)
+    return o
+
+class PyExpr(str):
+    __slots__ = ['filename', 'linenumber']
+    def __new__(cls, s, filename='x.rpy', linenumber=1):
+        self = str.__new__(cls, s); self.filename = filename; self.linenumber = linenumber; return self
+    def __getnewargs__(self):
+        return (str(self), self.filename, self.linenumber)
+PyExpr.__module__ = 'renpy.ast'; renpy_ast.PyExpr = PyExpr
+
+class PyCode(object):
+    __slots__ = ['source', 'location', 'mode', 'bytecode', 'hash', 'py']
+    def __init__(self, source, mode='exec'):
+        self.source = PyExpr(source); self.location = ('x.rpy', 1, 0); self.mode = mode; self.py = 3
+    def __getstate__(self):
+        return (1, self.source, self.location, self.mode, self.py)
+PyCode.__module__ = 'renpy.ast'; renpy_ast.PyCode = PyCode
+
+class Obj(object):
+    def __init__(self, **kw): self.__dict__.update(kw)
+Obj.__module__ = 'renpy.object'; sys.modules['renpy.object'].Object = Obj
+def obj(module, clsname, **kw):
+    c = getattr(sys.modules[module], clsname, None)
+    if c is None:
+        c = type(clsname, (Obj,), {'__module__': module}); setattr(sys.modules[module], clsname, c)
+    return c(**kw)
+
+E = PyExpr
+def ArgumentInfo(args, extrapos=None, extrakw=None):
+    return obj('renpy.ast', 'ArgumentInfo', arguments=args, extrapos=extrapos, extrakw=extrakw)
+def ParameterInfo(params, positional, extrapos=None, extrakw=None):
+    return obj('renpy.ast', 'ParameterInfo', parameters=params, positional=positional, extrapos=extrapos, extrakw=extrakw)
+def atl(module='renpy.atl', **kw): pass
+def RawBlock(stmts, animation=False): return obj('renpy.atl', 'RawBlock', statements=stmts, animation=animation)
+def MP(warper=None, duration='0', properties=(), expressions=(), splines=(), revolution=None, circles='0'):
+    return obj('renpy.atl', 'RawMultipurpose', warper=warper, duration=E(duration), properties=list(properties), expressions=list(expressions), splines=list(splines), revolution=revolution, circles=E(circles), warp_function=None)
+
+def say(who, what, **kw):
+    d = dict(who=who, who_fast=True, what=what, with_=None, interact=True, attributes=None, arguments=None, temporary_attributes=None, identifier=None, explicit_identifier=False)
+    d.update(kw)
+    return node('Say', **d)
+def tr(s, ident):
+    return [node('Translate', identifier=ident, language=None, block=[s], alternate=None), node('EndTranslate')]
+def init(prio, *block): return node('Init', block=list(block), priority=prio)
+def python(src, hide=False, store='store'): return node('Python', code=PyCode(src), hide=hide, store=store)
+def imspec(*name, at=(), tag=None, layer=None): return (tuple(name), None, tag, [E(a) for a in at], layer, None, [])
+
+sl = 'renpy.sl2.slast'
+def sld(disp_mod, disp_name, style, positional=(), keyword=(), children=()):
+    d = getattr(sys.modules[disp_mod], disp_name, None)
+    if d is None:
+        d = type(disp_name, (object,), {'__module__': disp_mod}); setattr(sys.modules[disp_mod], disp_name, d)
+    return obj(sl, 'SLDisplayable', displayable=d, style=style, positional=[E(p) for p in positional], keyword=[(k, E(v)) for k, v in keyword], children=list(children), variable=None)
+
+screen = obj(sl, 'SLScreen', name='say', parameters=ParameterInfo([('who', None), ('what', None)], ['who', 'what']),
+    modal='True', zorder='0', tag=None, variant='None', predict='None', layer="'screens'", sensitive='True', keyword=[('style_prefix', E('"say"'))],
+    children=[
+        sld('renpy.display.layout', 'Window', 'window', keyword=[('id', '"window"')], children=[
+            obj(sl, 'SLIf', entries=[(E('who is not None'), obj(sl, 'SLBlock', keyword=[], children=[sld('renpy.text.text', 'Text', 'text', positional=['who'], keyword=[('id', '"who"')])]))]),
+            sld('renpy.text.text', 'Text', 'text', positional=['what'], keyword=[('id', '"what"')]),
+        ]),
+        obj(sl, 'SLFor', variable='i', expression=E('range(3)'), index_expression=None, keyword=[], children=[
+            sld('renpy.ui', '_textbutton', 'button', positional=['"B"'], keyword=[('action', 'Return(i)')])]),
+        obj(sl, 'SLUse', target='other', args=ArgumentInfo([(None, E('1'))]), id=None, block=None),
+        sld('renpy.sl2.sldisplayables', 'sl2add', None, positional=['"logo.png"']),
+        obj(sl, 'SLPython', code=PyCode('x = 1')),
+    ])
+
+label_block = [
+    node('With', expr=E('None'), paired=E('fade')),
+    node('Scene', imspec=imspec('bg', 'room'), layer=None, atl=None),
+    node('With', expr=E('fade'), paired=None),
+    node('Show', imspec=imspec('eileen', 'happy', at=['left']), atl=None),
+    *tr(say('e', 'Hello "world"  x\nnext'), 'start_abc'),
+    *tr(say(None, 'Narration', with_=E('dissolve')), 'start_def'),
+    python('points += 1'),
+    python('a = 1\nb = 2\n'),
+    say('e', 'Which?', interact=False),
+    node('Menu', items=[('Left', E('points > 0'), [node('Jump', target='left', expression=False)]),
+                        ('Right', E('True'), [node('Call', label='right', arguments=None, expression=False), node('Label', name='_call_right', block=[], parameters=None, hide=False)])],
+         set=None, with_=None, has_caption=True, arguments=None, item_arguments=[None, None]),
+    node('If', entries=[(E('points > 1'), [say('e', 'many')]), (E('points'), [say('e', 'one')]), (E('True'), [say('e', 'none')])]),
+    node('While', condition=E('points < 3'), block=[python('points += 1')]),
+    node('UserStatement', line='play music "a.ogg"', parsed=None, block=[], translatable=False),
+    node('With', expr=E('dissolve'), paired=None),
+    node('Hide', imspec=imspec('eileen')),
+    node('Call', label=E('target'), arguments=ArgumentInfo([(None, E('1')), ('b', E('2'))], extrakw=E('kw')), expression=True),
+    node('Return', expression=None),
+]
+
+stmts = [
+    init(0, node('Define', varname='e', code=PyCode('Character("Eileen")', 'eval'), store='store', operator='=', index=None)),
+    init(0, node('Define', varname='x', code=PyCode('1', 'eval'), store='store.persistent_ns', operator='=', index=None)),
+    init(0, node('Default', varname='points', code=PyCode('0', 'eval'), store='store')),
+    init(-2, node('Define', varname='early', code=PyCode('True', 'eval'), store='store', operator='=', index=None)),
+    init(500, node('Image', imgname=('bg', 'room'), code=PyCode('"room.png"', 'eval'), atl=None)),
+    init(500, node('Image', imgname=('eileen', 'happy'), code=None, atl=RawBlock([
+        MP(expressions=[(E('"eileen.png"'), None)]),
+        MP(warper='linear', duration='1.0', properties=[('xalign', E('1.0'))]),
+        obj('renpy.atl', 'RawRepeat', repeats=None)]))),
+    init(0, node('Transform', varname='slide', store='store', parameters=ParameterInfo([('x', E('0.5')), ('y', None)], ['x'], extrapos='args', extrakw='kw'), atl=RawBlock([
+        MP(properties=[('xalign', E('x'))]),
+        obj('renpy.atl', 'RawParallel', blocks=[RawBlock([MP(warper='ease', duration='0.5', properties=[('alpha', E('1.0'))])]), RawBlock([MP(warper='pause', duration='0.5')])]),
+        obj('renpy.atl', 'RawChoice', choices=[(E('1.0'), RawBlock([MP(properties=[('alpha', E('0.0'))])])), (E('2.0'), RawBlock([MP(properties=[('alpha', E('0.5'))])]))]),
+        obj('renpy.atl', 'RawOn', handlers={'show': RawBlock([MP(warper='linear', duration='0.2', properties=[('alpha', E('1.0'))])])}),
+    ]))),
+    init(0, python('def f():\n    return 1\n')),
+    init(-2, python('x = 1')),
+    init(0, node('Style', style_name='big', parent='default', properties={'size': E('40'), 'color': E('"#fff"')}, clear=False, take=None, delattr=[], variant=None)),
+    init(-500, node('Screen', screen=screen)),
+    node('Label', name='start', block=label_block, parameters=None, hide=False),
+    node('Label', name='left', block=[node('Return', expression=E('1'))], parameters=ParameterInfo([('a', None), ('b', E('2'))], ['a', 'b']), hide=False),
+    node('TranslateString', language='russian', old='Hello', new='Привет', newloc=None),
+    node('TranslateString', language='russian', old='Bye', new='Пока', newloc=None),
+    node('Translate', identifier='start_abc', language='russian', block=[say('e', 'Привет')], alternate=None),
+]
+
+data = zlib.compress(pickle.dumps(({'version': 5003000, 'key': 'unlocked'}, stmts), 2))
+# Scripts of Ren'Py before 6.99 are just a compressed pickle.
+open('old.rpyc', 'wb').write(data)
+hdr = b'RENPY RPC2' + struct.pack('<III', 1, 46, len(data)) + struct.pack('<III', 2, 46 + len(data), 0) + struct.pack('<III', 0, 0, 0)
+open('script.rpyc', 'wb').write(hdr + data)
@@ -0,0 +1,169 @@
+package save
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kaey/gamearc/internal/pickle"
+)
+
+// toJSON converts unpickled value to a value encodable by encoding/json.
+func toJSON(v any) any {
+	c := &converter{refs: make(map[any]int), ids: make(map[any]int)}
+	c.count(v)
+
+	return c.convert(v)
+}
+
+type converter struct {
+	// refs counts references to lists, dicts and objects.
+	refs map[any]int
+
+	// ids are assigned to values referenced more than once when they are converted.
+	ids map[any]int
+}
+
+func (c *converter) count(v any) {
+	switch v := v.(type) {
+	case *pickle.List, *pickle.Dict, *pickle.Object:
+		c.refs[v]++
+		if c.refs[v] > 1 {
+			return
+		}
+	}
+
+	switch v := v.(type) {
+	case pickle.Tuple:
+		for _, i := range v {
+			c.count(i)
+		}
+	case *pickle.List:
+		for _, i := range v.Items {
+			c.count(i)
+		}
+	case *pickle.Dict:
+		for i := range v.Keys {
+			c.count(v.Keys[i])
+			c.count(v.Values[i])
+		}
+	case *pickle.Object:
+		c.count(v.Args)
+		c.count(v.State)
+		for _, i := range v.Items {
+			c.count(i)
+		}
+		if v.Dict != nil {
+			c.count(v.Dict)
+		}
+	}
+}
+
+func (c *converter) convert(v any) any {
+	switch v.(type) {
+	case *pickle.List, *pickle.Dict, *pickle.Object:
+		if id, ok := c.ids[v]; ok {
+			return map[string]any{"$ref": id}
+		}
+		if c.refs[v] > 1 {
+			c.ids[v] = len(c.ids) + 1
+		}
+	}
+
+	switch v := v.(type) {
+	case pickle.Tuple:
+		return c.array(v)
+	case *pickle.List:
+		return c.withID(v, c.array(v.Items))
+	case *pickle.Dict:
+		return c.withID(v, c.dict(v))
+	case *pickle.Object:
+		// Fields are converted in the order they are encoded, so that ids precede references.
+		res := map[string]any{"$class": v.Class.String()}
+		if len(v.Args) > 0 {
+			res["args"] = c.array(v.Args)
+		}
+		if v.Dict != nil {
+			res["dict"] = c.convert(v.Dict)
+		}
+		if len(v.Items) > 0 {
+			res["items"] = c.array(v.Items)
+		}
+		if v.State != nil {
+			res["state"] = c.convert(v.State)
+		}
+		return c.withID(v, res)
+	case pickle.Class:
+		return map[string]any{"$class": v.String()}
+	case pickle.Bytes:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return map[string]any{"$bytes": base64.StdEncoding.EncodeToString(v)}
+	case *big.Int:
+		return json.Number(v.String())
+	case float64:
+		// JSON has no NaN and infinities.
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		return v
+	}
+
+	return v
+}
+
+func (c *converter) array(items []any) []any {
+	res := make([]any, len(items))
+	for i, v := range items {
+		res[i] = c.convert(v)
+	}
+
+	return res
+}
+
+func (c *converter) dict(d *pickle.Dict) any {
+	obj := make(map[string]any, len(d.Keys))
+	for _, k := range d.Keys {
+		if _, ok := k.(string); !ok {
+			pairs := make([]any, len(d.Keys))
+			for i := range d.Keys {
+				pairs[i] = []any{c.convert(d.Keys[i]), c.convert(d.Values[i])}
+			}
+			return map[string]any{"$dict": pairs}
+		}
+	}
+
+	// Keys are sorted the same as encoding/json does.
+	order := make([]int, len(d.Keys))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int { return strings.Compare(d.Keys[a].(string), d.Keys[b].(string)) })
+	for _, i := range order {
+		obj[d.Keys[i].(string)] = c.convert(d.Values[i])
+	}
+
+	return obj
+}
+
+// withID adds "$id" to converted value if it is referenced more than once, lists are wrapped into {"$list": items}.
+func (c *converter) withID(v, converted any) any {
+	id, ok := c.ids[v]
+	if !ok {
+		return converted
+	}
+
+	switch converted := converted.(type) {
+	case map[string]any:
+		converted["$id"] = id
+		return converted
+	default:
+		return map[string]any{"$id": id, "$list": converted}
+	}
+}
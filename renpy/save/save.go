@@ -0,0 +1,119 @@
+// Package save reads Ren'Py save files.
+//
+// Save is a zip archive with entries:
+//
+//	log             pickle of (roots, log), store variables and rollback log
+//	screenshot.png  thumbnail shown in save slot
+//	json            metadata: _save_name, _renpy_version, _version, _game_runtime, _ctime and fields added by the game
+//	extra_info      save name
+//	renpy_version   version of Ren'Py which created the save
+//	signatures      signatures of the log, Ren'Py 8.1 and later
+//
+// Log is unpickled into generic values without Ren'Py classes, which is enough to inspect it as JSON.
+package save
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/kaey/gamearc/internal/arc"
+	"github.com/kaey/gamearc/internal/pickle"
+	"github.com/kaey/gamearc/zip"
+)
+
+type Save struct {
+	// Meta is decoded json entry, nil in saves of Ren'Py older than 6.99.
+	Meta map[string]any
+
+	a *zip.Archive
+}
+
+func OpenSave(r io.ReaderAt, size int64) (*Save, error) {
+	a, err := zip.OpenArchive(r, size)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.Stat("log"); err != nil {
+		return nil, fmt.Errorf("not a Ren'Py save: %w", err)
+	}
+
+	s := &Save{a: a}
+	data, err := a.ReadFile("json")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&s.Meta); err != nil {
+			return nil, fmt.Errorf("metadata: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// Entries returns all entries of the save archive.
+func (s *Save) Entries() []arc.Entry {
+	return s.a.Entries()
+}
+
+// Name returns save name entered by player, empty if there is none.
+func (s *Save) Name() string {
+	if name, ok := s.Meta["_save_name"].(string); ok {
+		return name
+	}
+	name, _ := s.a.ReadFile("extra_info")
+
+	return string(name)
+}
+
+// RenpyVersion returns version of Ren'Py which created the save, empty if it is unknown.
+func (s *Save) RenpyVersion() string {
+	if v, ok := s.Meta["_renpy_version"].([]any); ok {
+		parts := make([]string, len(v))
+		for i, p := range v {
+			parts[i] = fmt.Sprint(p)
+		}
+		return strings.Join(parts, ".")
+	}
+	v, _ := s.a.ReadFile("renpy_version")
+
+	return string(v)
+}
+
+// Screenshot returns png thumbnail of the save.
+func (s *Save) Screenshot() ([]byte, error) {
+	return s.a.ReadFile("screenshot.png")
+}
+
+// WriteJSON writes unpickled log as indented JSON.
+//
+// Tuples, lists and sets are arrays, dicts with string keys are objects, other dicts are
+// {"$dict": [[key, value], ...]}. Instances are {"$class": "module.Name", "args": ..., "state": ..., "items": ..., "dict": ...}
+// with empty fields omitted. Bytes which are not utf-8 are {"$bytes": base64}.
+// Lists, dicts and instances referenced more than once get "$id" field on the first occurrence
+// (lists become {"$id": id, "$list": items}) and are replaced with {"$ref": id} on the others,
+// as the log is full of cycles.
+func (s *Save) WriteJSON(w io.Writer) error {
+	f, err := s.a.Open("log")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	v, err := pickle.Load(f)
+	if err != nil {
+		return fmt.Errorf("log: %w", err)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(toJSON(v))
+}
@@ -0,0 +1,417 @@
+package tl
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kaey/gamearc/renpy/rpyc"
+)
+
+// keywords start statements which are not say statements. Blocks of these statements are skipped,
+// unless they are handled in sourceBlock.
+var keywords = map[string]bool{
+	"$": true, "call": true, "camera": true, "default": true, "define": true, "elif": true, "else": true,
+	"hide": true, "if": true, "image": true, "init": true, "jump": true, "label": true, "layeredimage": true,
+	"menu": true, "nvl": true, "old": true, "new": true, "pass": true, "pause": true, "play": true,
+	"python": true, "queue": true, "return": true, "rpy": true, "scene": true, "screen": true,
+	"show": true, "stop": true, "style": true, "testcase": true, "transform": true, "translate": true,
+	"voice": true, "while": true, "window": true, "with": true,
+}
+
+// translatable are user statements which are translated together with the following say statement.
+var translatable = []string{"voice", "nvl clear"}
+
+var (
+	stringPrefix = regexp.MustCompile(`^(?:"""|'''|"|'|` + "`" + `)`)
+	whitespace   = regexp.MustCompile(`[ \n]+`)
+)
+
+// logical is a logical line of source and lines indented under it.
+type logical struct {
+	text   string
+	line   int
+	indent int
+	block  []*logical
+}
+
+// source collects translations of .rpy script.
+// It is not a full parser, say statements are recognized as lines which are not other statements
+// and have a string preceded by optional character and image attributes.
+func (sc *script) source(data []byte) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	lines := splitLines(string(data))
+	sc.sourceBlock(nest(&lines, -1))
+}
+
+func (sc *script) sourceBlock(block []*logical) {
+	var group []stmt
+	for _, l := range block {
+		word, rest := firstWord(l.text)
+		switch word {
+		case "label":
+			name, _, _ := strings.Cut(rest, ":")
+			name, _, _ = strings.Cut(name, "(")
+			fields := strings.Fields(name)
+			if len(fields) == 0 {
+				continue
+			}
+			if strings.HasPrefix(fields[0], ".") {
+				global, _, _ := strings.Cut(sc.label, ".")
+				fields[0] = global + fields[0]
+			}
+			sc.setLabel(fields[0], len(fields) > 1 && fields[1] == "hide")
+			sc.sourceBlock(l.block)
+		case "menu":
+			// Named menu is also a label.
+			if name, _, _ := strings.Cut(strings.TrimSuffix(rest, ":"), "("); isName(strings.TrimSpace(name)) {
+				sc.setLabel(strings.TrimSpace(name), false)
+			}
+			sc.menu(l.block)
+		case "if", "elif", "else", "while":
+			sc.sourceBlock(l.block)
+		default:
+			if isTranslatable(l.text) {
+				group = append(group, stmt{code: l.text, line: l.line})
+				continue
+			}
+			if s, ok := parseSay(l, false); ok {
+				sc.add("", append(group, s))
+			}
+		}
+		group = nil
+	}
+}
+
+func (sc *script) menu(block []*logical) {
+	for _, l := range block {
+		if strings.HasPrefix(l.text, "set ") {
+			continue
+		}
+		// Caption or choice.
+		if text, rest, ok := stringLiteral(l.text); ok && (rest == "" || strings.HasSuffix(rest, ":")) {
+			sc.addString(l.line, text)
+			sc.sourceBlock(l.block)
+			continue
+		}
+		if s, ok := parseSay(l, true); ok {
+			sc.add("", []stmt{s})
+		}
+	}
+}
+
+func isTranslatable(text string) bool {
+	for _, t := range translatable {
+		if text == t || strings.HasPrefix(text, t+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitLines returns logical lines without comments. Lines are joined while a string or parenthesis is open,
+// newlines inside strings are kept.
+func splitLines(src string) []*logical {
+	var res []*logical
+	var b strings.Builder
+	var quote byte
+	depth, number, start := 0, 1, 1
+
+	flush := func() {
+		text := strings.TrimRightFunc(b.String(), unicode.IsSpace)
+		b.Reset()
+		trimmed := strings.TrimLeft(text, " \t")
+		if trimmed != "" {
+			res = append(res, &logical{text: trimmed, line: start, indent: len(text) - len(trimmed)})
+		}
+	}
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && i+1 < len(src) {
+				b.WriteByte(c)
+				i++
+				c = src[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '#':
+			for i+1 < len(src) && src[i+1] != '\n' {
+				i++
+			}
+			continue
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case (c == ')' || c == ']' || c == '}') && depth > 0:
+			depth--
+		case c == '\n' && depth == 0:
+			flush()
+			number++
+			start = number
+			continue
+		}
+		if c == '\n' {
+			number++
+		}
+		if c != '\r' {
+			b.WriteByte(c)
+		}
+	}
+	flush()
+
+	return res
+}
+
+// nest builds blocks of lines indented deeper than indent.
+func nest(lines *[]*logical, indent int) []*logical {
+	var res []*logical
+	for len(*lines) > 0 && (*lines)[0].indent > indent {
+		l := (*lines)[0]
+		*lines = (*lines)[1:]
+		l.block = nest(lines, l.indent)
+		res = append(res, l)
+	}
+
+	return res
+}
+
+func firstWord(text string) (word, rest string) {
+	if strings.HasPrefix(text, "$") {
+		return "$", text[1:]
+	}
+	end := strings.IndexFunc(text, func(r rune) bool { return !isNameRune(r) })
+	if end < 0 {
+		end = len(text)
+	}
+
+	return text[:end], strings.TrimSpace(text[end:])
+}
+
+func isNameRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isName(s string) bool {
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		return false
+	}
+	for _, r := range s {
+		if !isNameRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSay parses [who [attributes] [@ attributes]] "what" [nointeract] [id name] [(arguments)] [with expr]
+// and formats its code the same as Ren'Py does. Say in menu does not interact.
+func parseSay(l *logical, inMenu bool) (stmt, bool) {
+	var words []string
+	text := l.text
+	for {
+		if text == "" {
+			return stmt{}, false
+		}
+		if _, _, ok := stringLiteral(text); ok {
+			break
+		}
+		word, rest := firstWord(text)
+		switch {
+		case word == "" && (text[0] == '@' || text[0] == '-'):
+			word, rest = text[:1], strings.TrimSpace(text[1:])
+			if word == "-" {
+				word, rest = firstWord(rest)
+				word = "-" + word
+			}
+		case word == "", len(words) == 0 && keywords[word]:
+			return stmt{}, false
+		case strings.HasPrefix(rest, "."):
+			// Character in a namespace, e.g. store.e.
+			next, r := firstWord(rest[1:])
+			word, rest = word+"."+next, r
+		}
+		words = append(words, word)
+		text = rest
+	}
+
+	what, rest, _ := stringLiteral(text)
+	s := stmt{line: l.line, say: true}
+
+	// Who may be a string, in which case what is the second string.
+	if len(words) == 0 {
+		if second, r, ok := stringLiteral(rest); ok {
+			words = append(words, strings.TrimSpace(text[:len(text)-len(rest)]))
+			what, rest = second, r
+		}
+	}
+	if len(words) > 0 {
+		s.who = words[0]
+	}
+	s.what = what
+
+	code := append(words, rpyc.Quote(what))
+	var id, args, with string
+	nointeract := inMenu
+	for rest != "" {
+		var word string
+		word, rest = firstWord(rest)
+		switch {
+		case word == "nointeract":
+			nointeract = true
+		case word == "id":
+			id, rest = firstWord(rest)
+		case word == "with":
+			with, rest = rest, ""
+		case word == "" && rest[0] == '(':
+			end := closing(rest)
+			if end < 0 {
+				// Ren'Py fails to parse such line.
+				return stmt{}, false
+			}
+			args, rest = normalizeArgs(rest[1:end]), strings.TrimSpace(rest[end+1:])
+		default:
+			// Anything else is kept as is.
+			code = append(code, strings.TrimSpace(word+" "+rest))
+			rest = ""
+		}
+	}
+	if nointeract {
+		code = append(code, "nointeract")
+	}
+	if id != "" {
+		code = append(code, "id", id)
+	}
+	if args != "" {
+		code = append(code, args)
+	}
+	if with != "" {
+		code = append(code, "with", with)
+	}
+	s.code = strings.Join(code, " ")
+
+	return s, true
+}
+
+// stringLiteral parses Ren'Py string at the start of text and returns its value and the rest of text.
+// Unlike python strings, runs of whitespace collapse into single space and {, [ and % escapes are doubled,
+// so that they are taken literally by text interpolation.
+func stringLiteral(text string) (value, rest string, ok bool) {
+	q := stringPrefix.FindString(text)
+	if q == "" {
+		return "", "", false
+	}
+	i := len(q)
+	for ; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(text[i:], q) {
+			break
+		}
+	}
+	if i >= len(text) {
+		return "", "", false
+	}
+
+	s := whitespace.ReplaceAllString(text[len(q):i], " ")
+	rest = strings.TrimSpace(text[i+len(q):])
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case '{', '[', '%':
+			b.WriteByte(c)
+			b.WriteByte(c)
+		case 'n':
+			b.WriteByte('\n')
+		case 'u':
+			end := i + 1
+			for end < len(s) && end < i+5 && strings.IndexByte("0123456789abcdefABCDEF", s[end]) >= 0 {
+				end++
+			}
+			if n, err := strconv.ParseUint(s[i+1:end], 16, 32); err == nil {
+				b.WriteRune(rune(n))
+				i = end - 1
+			} else {
+				b.WriteByte(c)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String(), rest, true
+}
+
+// skipString returns index of the last byte of string literal starting at s[i], or i if there is none.
+func skipString(s string, i int) int {
+	if _, rest, ok := stringLiteral(s[i:]); ok {
+		return len(strings.TrimRight(s[:len(s)-len(rest)], " ")) - 1
+	}
+	return i
+}
+
+// closing returns index of parenthesis closing the one at the start of s, -1 if it is not closed.
+func closing(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case '"', '\'':
+			i = skipString(s, i)
+		}
+	}
+
+	return -1
+}
+
+// normalizeArgs formats arguments the same as Ren'Py does: name=value separated by comma and space.
+func normalizeArgs(s string) string {
+	var args []string
+	depth, start := 0, 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) {
+			switch s[i] {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				depth--
+			case '"', '\'':
+				i = skipString(s, i)
+			}
+			if s[i] != ',' || depth > 0 {
+				continue
+			}
+		}
+
+		arg := strings.TrimSpace(s[start:i])
+		start = i + 1
+		if arg == "" {
+			continue
+		}
+		if name, value, ok := strings.Cut(arg, "="); ok && isName(strings.TrimSpace(name)) && !strings.HasPrefix(value, "=") {
+			arg = strings.TrimSpace(name) + "=" + strings.TrimSpace(value)
+		}
+		args = append(args, arg)
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
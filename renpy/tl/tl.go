@@ -0,0 +1,275 @@
+// Package tl extracts translatable dialogue and menu choices from Ren'Py scripts
+// and writes them as Ren'Py translation files, CSV or PO.
+//
+// Dialogue identifiers are the same as Ren'Py assigns to translate blocks:
+// compiled scripts already contain them, for source scripts they are computed
+// from the last label and md5 of dialogue code.
+package tl
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/kaey/gamearc/internal/pickle"
+	"github.com/kaey/gamearc/renpy/rpyc"
+)
+
+// Dialogue is a translate block, a say statement with statements translated together with it, such as voice.
+type Dialogue struct {
+	ID string
+
+	// File is path of the script relative to base directory of the game, e.g. game/script.rpy.
+	File string
+	Line int
+
+	Who  string
+	What string
+
+	// Code is Ren'Py code of statements in the block.
+	Code []string
+}
+
+// String is a translatable string, such as a menu choice.
+// Strings are translated by their text, so each text is extracted only once.
+type String struct {
+	File string
+	Line int
+	Text string
+}
+
+type Translations struct {
+	Dialogue []Dialogue
+	Strings  []String
+
+	seen map[string]bool
+}
+
+// Extract reads translations of all scripts in fsys, which is usually an opened archive or game directory.
+// Compiled .rpyc scripts are preferred over .rpy with the same name, tl directory is skipped.
+func Extract(fsys fs.FS) (*Translations, error) {
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name == "tl" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		switch path.Ext(name) {
+		case ".rpyc":
+			names = append(names, name)
+		case ".rpy":
+			if _, err := fs.Stat(fsys, name+"c"); err != nil {
+				names = append(names, name)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Translations{seen: make(map[string]bool)}
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		sc := &script{t: t, file: "game/" + strings.TrimSuffix(name, "c"), ids: make(map[string]bool)}
+		if path.Ext(name) == ".rpy" {
+			sc.source(data)
+			continue
+		}
+
+		stmts, err := rpyc.Load(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		sc.compiled(stmts)
+	}
+
+	return t, nil
+}
+
+// stmt is a statement of translate block.
+type stmt struct {
+	code string
+	line int
+
+	say       bool
+	who, what string
+}
+
+// script collects translations of a single file.
+type script struct {
+	t    *Translations
+	file string
+
+	// label is the last label without leading underscore, it prefixes computed identifiers.
+	label string
+	ids   map[string]bool
+}
+
+func (sc *script) setLabel(name string, hide bool) {
+	if !hide && !strings.HasPrefix(name, "_") {
+		sc.label = name
+	}
+}
+
+// add adds translate block. Identifier is computed if it is empty.
+func (sc *script) add(id string, block []stmt) {
+	d := Dialogue{File: sc.file}
+	say := false
+	for _, s := range block {
+		d.Code = append(d.Code, s.code)
+		if s.say && !say {
+			d.Line, d.Who, d.What = s.line, s.who, s.what
+			say = true
+		}
+	}
+	if !say {
+		return
+	}
+
+	if id == "" {
+		id = sc.identifier(block)
+	}
+	sc.ids[id] = true
+	d.ID = id
+
+	sc.t.Dialogue = append(sc.t.Dialogue, d)
+}
+
+// identifier returns label followed by md5 of code of all statements in the block, made unique within the file
+// by a numeric suffix. Label dots are replaced by underscores.
+func (sc *script) identifier(block []stmt) string {
+	h := md5.New()
+	for _, s := range block {
+		io.WriteString(h, s.code+"\r\n")
+	}
+
+	base := hex.EncodeToString(h.Sum(nil))[:8]
+	if sc.label != "" {
+		base = strings.ReplaceAll(sc.label, ".", "_") + "_" + base
+	}
+	id := base
+	for i := 1; sc.ids[id]; i++ {
+		id = fmt.Sprintf("%s_%d", base, i)
+	}
+
+	return id
+}
+
+func (sc *script) addString(line int, text string) {
+	if text == "" || sc.t.seen[text] {
+		return
+	}
+	sc.t.seen[text] = true
+	sc.t.Strings = append(sc.t.Strings, String{File: sc.file, Line: line, Text: text})
+}
+
+// compiled collects translations of compiled statements.
+func (sc *script) compiled(stmts []*pickle.Object) {
+	for _, s := range stmts {
+		switch s.Class.Name {
+		case "Label":
+			sc.setLabel(str(s.Get("name")), s.Get("hide") == true)
+			sc.compiled(objects(s.Get("block")))
+		case "Translate":
+			if s.Get("language") != nil {
+				continue
+			}
+			var block []stmt
+			for _, c := range objects(s.Get("block")) {
+				block = append(block, compiledStmt(c))
+			}
+			sc.add(str(s.Get("identifier")), block)
+		case "TranslateSay":
+			if s.Get("language") == nil {
+				sc.add(str(s.Get("identifier")), []stmt{compiledStmt(s)})
+			}
+		case "Say":
+			// Scripts compiled before Ren'Py 6.15 have no translate blocks.
+			sc.add("", []stmt{compiledStmt(s)})
+		case "Menu":
+			line := int(num(s.Get("linenumber")))
+			for _, item := range list(s.Get("items")) {
+				if t := list(item); len(t) == 3 {
+					sc.addString(line, str(t[0]))
+					sc.compiled(objects(t[2]))
+				}
+			}
+		case "If":
+			for _, entry := range list(s.Get("entries")) {
+				if t := list(entry); len(t) == 2 {
+					sc.compiled(objects(t[1]))
+				}
+			}
+		case "While":
+			sc.compiled(objects(s.Get("block")))
+		}
+	}
+}
+
+func compiledStmt(s *pickle.Object) stmt {
+	st := stmt{code: rpyc.Code(s), line: int(num(s.Get("linenumber")))}
+	if s.Class.Name == "Say" || s.Class.Name == "TranslateSay" {
+		st.say = true
+		st.who = str(s.Get("who"))
+		st.what = str(s.Get("what"))
+	}
+
+	return st
+}
+
+func str(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case *pickle.Object:
+		// PyExpr is a subclass of str.
+		if len(v.Args) > 0 {
+			return str(v.Args[0])
+		}
+	}
+
+	return ""
+}
+
+func num(v any) int64 {
+	n, _ := v.(int64)
+	return n
+}
+
+func list(v any) []any {
+	switch v := v.(type) {
+	case *pickle.List:
+		return v.Items
+	case pickle.Tuple:
+		return v
+	}
+
+	return nil
+}
+
+func objects(v any) []*pickle.Object {
+	var res []*pickle.Object
+	for _, o := range list(v) {
+		if o, ok := o.(*pickle.Object); ok {
+			res = append(res, o)
+		}
+	}
+
+	return res
+}
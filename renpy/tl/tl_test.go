@@ -0,0 +1,71 @@
+package tl
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+const source = `label start:
+    # Example from Ren'Py documentation, which shows it translated as start_636ae3f5.
+    e "Thank you for taking a look at the Ren'Py translation framework."
+
+    voice "v1.ogg"
+    e "Hello there."
+    e "Hello there."
+
+label .sub:
+    "Narration."
+
+    menu:
+        e "Choose."
+        "Yes":
+            pass
+        "No":
+            pass
+
+label _hidden:
+    e "Hello there."
+    e "Unclosed" (
+`
+
+func TestExtractSource(t *testing.T) {
+	tr, err := Extract(fstest.MapFS{"script.rpy": {Data: []byte(source)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		id   string
+		line int
+		who  string
+		what string
+	}{
+		{"start_636ae3f5", 3, "e", "Thank you for taking a look at the Ren'Py translation framework."},
+		{"start_074952a5", 6, "e", "Hello there."},
+		{"start_496d9b91", 7, "e", "Hello there."},
+		{"start_sub_822c98ab", 10, "", "Narration."},
+		{"start_sub_0b1c89fc", 13, "e", "Choose."},
+		// Labels starting with underscore don't change identifiers.
+		{"start_sub_496d9b91", 20, "e", "Hello there."},
+	}
+	if len(tr.Dialogue) != len(want) {
+		t.Fatalf("got %d dialogue, want %d: %+v", len(tr.Dialogue), len(want), tr.Dialogue)
+	}
+	for i, w := range want {
+		d := tr.Dialogue[i]
+		if d.ID != w.id || d.Line != w.line || d.Who != w.who || d.What != w.what {
+			t.Errorf("dialogue %d: got %s:%d %s %q, want %s:%d %s %q", i, d.ID, d.Line, d.Who, d.What, w.id, w.line, w.who, w.what)
+		}
+		if d.File != "game/script.rpy" {
+			t.Errorf("dialogue %d: got file %q", i, d.File)
+		}
+	}
+
+	var strs []string
+	for _, s := range tr.Strings {
+		strs = append(strs, s.Text)
+	}
+	if len(strs) != 2 || strs[0] != "Yes" || strs[1] != "No" {
+		t.Errorf("got strings %q", strs)
+	}
+}
@@ -0,0 +1,116 @@
+package tl
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/kaey/gamearc/renpy/rpyc"
+)
+
+// TL returns Ren'Py translation files for language lang, keyed by path relative to game directory,
+// e.g. tl/french/script.rpy. Like the files Ren'Py generates, they contain original text
+// which is to be replaced by translation.
+func (t *Translations) TL(lang string) map[string][]byte {
+	files := make(map[string]*strings.Builder)
+	file := func(name string) *strings.Builder {
+		name = path.Join("tl", lang, strings.TrimPrefix(name, "game/"))
+		b, ok := files[name]
+		if !ok {
+			b = new(strings.Builder)
+			b.WriteString("\ufeff")
+			files[name] = b
+		}
+		return b
+	}
+
+	for _, d := range t.Dialogue {
+		b := file(d.File)
+		fmt.Fprintf(b, "# %s:%d\n", d.File, d.Line)
+		fmt.Fprintf(b, "translate %s %s:\n\n", lang, d.ID)
+		for _, code := range d.Code {
+			fmt.Fprintf(b, "    # %s\n", code)
+		}
+		for _, code := range d.Code {
+			fmt.Fprintf(b, "    %s\n", code)
+		}
+		b.WriteString("\n")
+	}
+
+	var order []string
+	strs := make(map[string][]String)
+	for _, s := range t.Strings {
+		if _, ok := strs[s.File]; !ok {
+			order = append(order, s.File)
+		}
+		strs[s.File] = append(strs[s.File], s)
+	}
+	for _, name := range order {
+		b := file(name)
+		fmt.Fprintf(b, "translate %s strings:\n\n", lang)
+		for _, s := range strs[name] {
+			fmt.Fprintf(b, "    # %s:%d\n", s.File, s.Line)
+			fmt.Fprintf(b, "    old %s\n", rpyc.Quote(s.Text))
+			fmt.Fprintf(b, "    new %s\n\n", rpyc.Quote(s.Text))
+		}
+	}
+
+	res := make(map[string][]byte, len(files))
+	for name, b := range files {
+		res[name] = []byte(b.String())
+	}
+
+	return res
+}
+
+// WriteCSV writes dialogue and strings as CSV with columns id, character, text, file and line.
+// Strings have no id, Ren'Py translates them by text.
+func (t *Translations) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "character", "text", "file", "line"})
+	for _, d := range t.Dialogue {
+		cw.Write([]string{d.ID, d.Who, d.What, d.File, strconv.Itoa(d.Line)})
+	}
+	for _, s := range t.Strings {
+		cw.Write([]string{"", "", s.Text, s.File, strconv.Itoa(s.Line)})
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// WritePO writes dialogue and strings as gettext PO file for language lang with empty translations.
+// Dialogue id is used as message context, character is written as extracted comment.
+func (t *Translations) WritePO(w io.Writer, lang string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "msgid \"\"\nmsgstr \"\"\n")
+	fmt.Fprintf(bw, "%s\n", poQuote("Language: "+lang+"\n"))
+	fmt.Fprintf(bw, "%s\n", poQuote("MIME-Version: 1.0\n"))
+	fmt.Fprintf(bw, "%s\n", poQuote("Content-Type: text/plain; charset=UTF-8\n"))
+	fmt.Fprintf(bw, "%s\n", poQuote("Content-Transfer-Encoding: 8bit\n"))
+
+	for _, d := range t.Dialogue {
+		fmt.Fprintf(bw, "\n#: %s:%d\n", d.File, d.Line)
+		if d.Who != "" {
+			fmt.Fprintf(bw, "#. %s\n", d.Who)
+		}
+		fmt.Fprintf(bw, "msgctxt %s\n", poQuote(d.ID))
+		fmt.Fprintf(bw, "msgid %s\nmsgstr \"\"\n", poQuote(d.What))
+	}
+	for _, s := range t.Strings {
+		fmt.Fprintf(bw, "\n#: %s:%d\n", s.File, s.Line)
+		fmt.Fprintf(bw, "msgid %s\nmsgstr \"\"\n", poQuote(s.Text))
+	}
+
+	return bw.Flush()
+}
+
+var poReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+
+func poQuote(s string) string {
+	return `"` + poReplacer.Replace(s) + `"`
+}
@@ -7,13 +7,19 @@ import (
 	"io"
 	"path"
 	"strings"
+
+	"github.com/kaey/gamearc/internal/arc"
 )
 
 type Archive struct {
+	*arc.FS
+
 	r    io.ReaderAt
 	size int64
 
-	Files []File
+	// Version is 1 for RPG Maker XP and VX archives, 3 for VX Ace.
+	Version int
+	Files   []File
 }
 
 type File struct {
@@ -28,21 +34,38 @@ func (f *File) Path() string {
 	return f.path
 }
 
+func (f *File) Offset() int64 {
+	return f.offset
+}
+
+func (f *File) Size() int64 {
+	return f.size
+}
+
 func (f *File) Reader() *io.SectionReader {
 	return io.NewSectionReader(&decryptReaderAt{r: f.r, key: f.key, startOffset: f.offset}, f.offset, f.size)
 }
 
+func (f *File) Open() (io.ReadSeeker, error) {
+	return f.Reader(), nil
+}
+
+func (f *File) Meta() map[string]any {
+	return map[string]any{"key": f.key}
+}
+
 func OpenArchive(r io.ReaderAt, size int64) (*Archive, error) {
 	a := &Archive{r: r, size: size}
 	if err := a.readIndex(); err != nil {
 		return nil, fmt.Errorf("read index: %w", err)
 	}
+	a.FS = arc.NewFS(arc.Entries(a.Files))
 
 	return a, nil
 }
 
 func (a *Archive) readIndex() error {
-	var header [12]byte
+	var header [8]byte
 	if _, err := a.r.ReadAt(header[:], 0); err != nil {
 		return err
 	}
@@ -51,11 +74,75 @@ func (a *Archive) readIndex() error {
 		return fmt.Errorf("expected rgss header %q, got %q", expected, got)
 	}
 
-	if expected, got := byte(0x3), header[7]; expected != got {
-		return fmt.Errorf("expected rgss version %q, got %q", expected, got)
+	a.Version = int(header[7])
+	switch a.Version {
+	case 1:
+		return a.readIndexV1()
+	case 3:
+		return a.readIndexV3()
+	}
+
+	return fmt.Errorf("unsupported rgss version %d", a.Version)
+}
+
+// readIndexV1 reads archives of RPG Maker XP and VX. They have no index, each file is preceded by
+// its name and size, encrypted with a key which starts at 0xDEADCAFE and is advanced after every
+// encrypted uint32 and every byte of name. File data is encrypted with the key following its size.
+func (a *Archive) readIndexV1() error {
+	key := uint32(0xDEADCAFE)
+	next := func(v uint32) uint32 {
+		v ^= key
+		key = key*7 + 3
+		return v
+	}
+
+	offset := int64(8)
+	for offset < a.size {
+		var buf [4]byte
+		if _, err := a.r.ReadAt(buf[:], offset); err != nil {
+			return err
+		}
+		offset += 4
+		pathlen := next(le.Uint32(buf[:]))
+		if int64(pathlen) > a.size-offset {
+			return fmt.Errorf("path length %d beyond end of file", pathlen)
+		}
+
+		pathb := make([]byte, pathlen)
+		if _, err := a.r.ReadAt(pathb, offset); err != nil {
+			return err
+		}
+		offset += int64(pathlen)
+		for i := range pathb {
+			pathb[i] ^= byte(key)
+			key = key*7 + 3
+		}
+
+		if _, err := a.r.ReadAt(buf[:], offset); err != nil {
+			return err
+		}
+		offset += 4
+		filesize := next(le.Uint32(buf[:]))
+		if int64(filesize) > a.size-offset {
+			return fmt.Errorf("file size %d beyond end of file", filesize)
+		}
+
+		if err := a.addFile(pathb, offset, int64(filesize), key); err != nil {
+			return err
+		}
+		offset += int64(filesize)
 	}
 
-	key := le.Uint32(header[8:12])*9 + 3
+	return nil
+}
+
+// readIndexV3 reads archives of RPG Maker VX Ace, which have index at the start.
+func (a *Archive) readIndexV3() error {
+	var header [4]byte
+	if _, err := a.r.ReadAt(header[:], 8); err != nil {
+		return err
+	}
+	key := le.Uint32(header[:])*9 + 3
 
 	offset := int64(12)
 	for {
@@ -81,32 +168,37 @@ func (a *Archive) readIndex() error {
 		}
 		offset += int64(pathlen)
 
-		// Decrypt path and replace backslashes with forward slashes.
 		keyb := [...]byte{byte(key), byte(key >> 8), byte(key >> 16), byte(key >> 24)}
 		for i := range pathb {
 			pathb[i] ^= keyb[i%4]
-			if pathb[i] == '\\' {
-				pathb[i] = '/'
-			}
 		}
 
-		p := path.Clean(string(pathb))
-		if path.IsAbs(p) {
-			return fmt.Errorf("archive contains a file with absolute path: %q", p)
-		}
-		if strings.Split(p, "/")[0] == ".." {
-			return fmt.Errorf("archive contains a file with path that leads outside of its root: %q", p)
+		if err := a.addFile(pathb, int64(fileoffset), int64(filesize), filekey); err != nil {
+			return err
 		}
-		a.Files = append(a.Files, File{
-			r:      a.r,
-			path:   p,
-			offset: int64(fileoffset),
-			size:   int64(filesize),
-			key:    filekey,
-		})
 	}
 }
 
+// addFile adds a file with decrypted path, backslashes in path are replaced with forward slashes.
+func (a *Archive) addFile(pathb []byte, offset, size int64, key uint32) error {
+	p := path.Clean(strings.ReplaceAll(string(pathb), "\\", "/"))
+	if path.IsAbs(p) {
+		return fmt.Errorf("archive contains a file with absolute path: %q", p)
+	}
+	if strings.Split(p, "/")[0] == ".." {
+		return fmt.Errorf("archive contains a file with path that leads outside of its root: %q", p)
+	}
+	a.Files = append(a.Files, File{
+		r:      a.r,
+		path:   p,
+		offset: offset,
+		size:   size,
+		key:    key,
+	})
+
+	return nil
+}
+
 type decryptReaderAt struct {
 	r           io.ReaderAt
 	key         uint32
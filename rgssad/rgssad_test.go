@@ -0,0 +1,66 @@
+package rgssad
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestOpenV1 reads testdata/v1.rgssad, generated by testdata/gen.py.
+func TestOpenV1(t *testing.T) {
+	data, err := os.ReadFile("testdata/v1.rgssad")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actors []byte
+	for range 4 {
+		for i := range 256 {
+			actors = append(actors, byte(i))
+		}
+	}
+	actors = append(actors, "tail"...)
+
+	want := []struct {
+		path string
+		data []byte
+	}{
+		{"Data/Actors.rxdata", actors},
+		{"Graphics/Pictures/a.png", []byte("\x89PNG")},
+		{"empty.txt", nil},
+		{"Audio/BGM/b.ogg", []byte(strings.Repeat("OggS", 3) + "x")},
+	}
+
+	a, err := OpenArchive(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Version != 1 {
+		t.Errorf("version: got %d, want 1", a.Version)
+	}
+	if len(a.Files) != len(want) {
+		t.Fatalf("got %d files, want %d", len(a.Files), len(want))
+	}
+	for i, w := range want {
+		f := &a.Files[i]
+		if f.Path() != w.path {
+			t.Errorf("file %d: got path %q, want %q", i, f.Path(), w.path)
+		}
+		got, err := io.ReadAll(f.Reader())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, w.data) {
+			t.Errorf("%s: got %q, want %q", w.path, got, w.data)
+		}
+	}
+
+	// Truncated archive is an error rather than a panic or a short file.
+	for _, n := range []int{9, 20, len(data) - 1} {
+		if _, err := OpenArchive(bytes.NewReader(data[:n]), int64(n)); err == nil {
+			t.Errorf("truncated to %d bytes: expected error", n)
+		}
+	}
+}
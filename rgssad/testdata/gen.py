@@ -0,0 +1,37 @@
+# Generates v1.rgssad used by rgssad tests: python3 gen.py
+# It is written independently of the Go code, following the format used by RPG Maker XP and VX.
+import struct
+
+
+def next_key(key):
+    return (key * 7 + 3) & 0xFFFFFFFF
+
+
+files = [
+    ("Data\\Actors.rxdata", bytes(range(256)) * 4 + b"tail"),
+    ("Graphics\\Pictures\\a.png", b"\x89PNG"),
+    ("empty.txt", b""),
+    ("Audio\\BGM\\b.ogg", b"OggS" * 3 + b"x"),
+]
+
+out = bytearray(b"RGSSAD\x00\x01")
+key = 0xDEADCAFE
+for name, data in files:
+    name = name.encode()
+    out += struct.pack("<I", len(name) ^ key)
+    key = next_key(key)
+    for b in name:
+        out.append(b ^ (key & 0xFF))
+        key = next_key(key)
+    out += struct.pack("<I", len(data) ^ key)
+    key = next_key(key)
+
+    # Data is xored with key current after size, which advances every 4 bytes.
+    file_key = key
+    for i, b in enumerate(data):
+        if i > 0 and i % 4 == 0:
+            file_key = next_key(file_key)
+        out.append(b ^ ((file_key >> (8 * (i % 4))) & 0xFF))
+
+with open("v1.rgssad", "wb") as f:
+    f.write(out)
@@ -0,0 +1,177 @@
+package rgssad
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"math/rand/v2"
+	"strings"
+)
+
+// Writer creates RGSSAD v3 archives.
+// Files are collected with Add or AddFS and written out by Close,
+// because index with file offsets precedes file data.
+type Writer struct {
+	w     io.Writer
+	seed  uint32
+	files []writerFile
+}
+
+type writerFile struct {
+	path string
+	size int64
+	key  uint32
+	open func() (io.ReadCloser, error)
+}
+
+// NewWriter returns a Writer with random base key.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, seed: rand.Uint32()}
+}
+
+// SetKey sets the value stored in archive header, actual base key is derived from it as seed*9+3.
+func (w *Writer) SetKey(seed uint32) {
+	w.seed = seed
+}
+
+// Add adds a file with random key. Open is called once when the archive is written
+// and must return exactly size bytes.
+func (w *Writer) Add(name string, size int64, open func() (io.ReadCloser, error)) error {
+	return w.AddWithKey(name, size, rand.Uint32(), open)
+}
+
+// AddWithKey is like Add, but uses provided file key.
+func (w *Writer) AddWithKey(name string, size int64, key uint32, open func() (io.ReadCloser, error)) error {
+	if !fs.ValidPath(name) || name == "." {
+		return fmt.Errorf("bad path: %q", name)
+	}
+	if size < 0 || size > math.MaxUint32 {
+		return fmt.Errorf("%s: bad size %d", name, size)
+	}
+
+	w.files = append(w.files, writerFile{
+		path: name,
+		size: size,
+		key:  key,
+		open: open,
+	})
+
+	return nil
+}
+
+// AddFS adds all regular files from fsys.
+func (w *Writer) AddFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		return w.Add(name, info.Size(), func() (io.ReadCloser, error) {
+			return fsys.Open(name)
+		})
+	})
+}
+
+// Close writes the archive. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	key := w.seed*9 + 3
+
+	// Index consists of 12 bytes header, 16 bytes + path for each file and 16 bytes terminator.
+	offset := int64(12 + 16)
+	for _, f := range w.files {
+		offset += 16 + int64(len(f.path))
+	}
+
+	bw := bufio.NewWriter(w.w)
+
+	var header [12]byte
+	copy(header[0:8], "RGSSAD\000\003")
+	le.PutUint32(header[8:12], w.seed)
+	bw.Write(header[:])
+
+	keyb := [...]byte{byte(key), byte(key >> 8), byte(key >> 16), byte(key >> 24)}
+	for _, f := range w.files {
+		if offset+f.size > math.MaxUint32 {
+			return errors.New("archive is too large")
+		}
+
+		var entry [16]byte
+		le.PutUint32(entry[0:4], uint32(offset)^key)
+		le.PutUint32(entry[4:8], uint32(f.size)^key)
+		le.PutUint32(entry[8:12], f.key^key)
+		le.PutUint32(entry[12:16], uint32(len(f.path))^key)
+		bw.Write(entry[:])
+
+		pathb := []byte(strings.ReplaceAll(f.path, "/", "\\"))
+		for i := range pathb {
+			pathb[i] ^= keyb[i%4]
+		}
+		bw.Write(pathb)
+
+		offset += f.size
+	}
+
+	// Terminator, decrypts to zero offset.
+	var entry [16]byte
+	for i := range entry {
+		entry[i] = keyb[i%4]
+	}
+	bw.Write(entry[:])
+
+	for _, f := range w.files {
+		if err := writeFile(bw, f); err != nil {
+			return fmt.Errorf("%s: %w", f.path, err)
+		}
+	}
+
+	return bw.Flush()
+}
+
+func writeFile(w io.Writer, f writerFile) error {
+	r, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	n, err := io.Copy(&encryptWriter{w: w, key: f.key}, io.LimitReader(r, f.size))
+	if err != nil {
+		return err
+	}
+	if n != f.size {
+		return fmt.Errorf("expected %d bytes, got %d", f.size, n)
+	}
+
+	return nil
+}
+
+// encryptWriter is a streaming counterpart of decryptReaderAt.
+type encryptWriter struct {
+	w   io.Writer
+	key uint32
+	n   int
+}
+
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	buf := make([]byte, len(p))
+	for i := range p {
+		if w.n > 0 && w.n%4 == 0 {
+			w.key = w.key*7 + 3
+		}
+		buf[i] = p[i] ^ byte(w.key>>(8*(w.n%4)))
+		w.n++
+	}
+
+	return w.w.Write(buf)
+}
@@ -0,0 +1,99 @@
+package rgssad
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	files := []struct {
+		name string
+		data string
+		key  uint32
+	}{
+		{"Game.ini", "[Game]\r\nTitle=Test\r\n", 0xDEADBEEF},
+		{"Data/Map001.rvdata2", strings.Repeat("map data ", 100), 0x12345678},
+		{"Graphics/Pictures/empty.png", "", 0},
+		{"Audio/BGM/Theme.ogg", "odd", 0xFFFFFFFF},
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetKey(0x1234)
+	for _, f := range files {
+		err := w.AddWithKey(f.name, int64(len(f.data)), f.key, func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(f.data)), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := OpenArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Version != 3 {
+		t.Errorf("version: got %d, want 3", a.Version)
+	}
+	if len(a.Files) != len(files) {
+		t.Fatalf("got %d files, want %d", len(a.Files), len(files))
+	}
+	for i, f := range files {
+		got := &a.Files[i]
+		if got.Path() != f.name {
+			t.Errorf("file %d: got path %q, want %q", i, got.Path(), f.name)
+		}
+		if got.Meta()["key"] != f.key {
+			t.Errorf("%s: got key %v, want %#x", f.name, got.Meta()["key"], f.key)
+		}
+		data, err := io.ReadAll(got.Reader())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != f.data {
+			t.Errorf("%s: got %q, want %q", f.name, data, f.data)
+		}
+	}
+}
+
+func TestWriterAddFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Game.ini":               {Data: []byte("[Game]\r\n")},
+		"Data/Scripts.rvdata2":   {Data: []byte("scripts")},
+		"Graphics/Titles1/a.png": {Data: []byte{0x89, 'P', 'N', 'G'}},
+		"Graphics/Titles1/empty": {Data: nil},
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.AddFS(fsys); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := OpenArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Files) != len(fsys) {
+		t.Fatalf("got %d files, want %d", len(a.Files), len(fsys))
+	}
+	for name, f := range fsys {
+		data, err := a.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, f.Data) {
+			t.Errorf("%s: got %q, want %q", name, data, f.Data)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package rpa
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxHeaderSize limits the length of header line.
+const maxHeaderSize = 256
+
+// Header describes where the index of an archive is and how to decode it.
+type Header struct {
+	// IndexOffset is offset of zlib compressed pickle of the index.
+	IndexOffset int64
+
+	// Key is xored with offsets and sizes in the index.
+	Key int64
+
+	// Index, if not nil, is read instead of data at IndexOffset,
+	// for formats which keep the index elsewhere or obfuscate it.
+	Index io.Reader
+
+	// File, if not nil, is called for every index entry with its path, offset and size after they are xored with Key,
+	// and returns actual offset and size of the file data.
+	File func(path string, offset, size int64) (int64, int64, error)
+}
+
+// HeaderParser parses first line of an archive without trailing newline.
+// Archive itself is passed for formats which keep the key outside of the header line.
+type HeaderParser func(line string, r io.ReaderAt, size int64) (*Header, error)
+
+// headers maps magic, the first word of header line, to its parser.
+var headers = map[string]HeaderParser{
+	"RPA-3.0": parseRPA3,
+	"RPA-2.0": parseRPA2,
+	"RPA-3.2": parseRPA32,
+	"ALT-1.0": parseALT1,
+	"ZiX-12A": parseZiX,
+	"ZiX-12B": parseZiX,
+}
+
+// RegisterHeader registers parser of archives which header line starts with magic followed by a space,
+// replacing any parser registered for it before, including built-in ones.
+// It is meant to be called from init functions and must not be called concurrently with OpenArchive.
+func RegisterHeader(magic string, parse HeaderParser) {
+	headers[magic] = parse
+}
+
+// HasMagic reports whether header starts with a magic of a registered parser.
+func HasMagic(header []byte) bool {
+	magic, _, ok := strings.Cut(string(header), " ")
+	return ok && headers[magic] != nil
+}
+
+// parseRPA3 parses "RPA-3.0 offset key...", Ren'Py xors all the keys together.
+func parseRPA3(line string, _ io.ReaderAt, _ int64) (*Header, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return nil, errors.New("incomplete header")
+	}
+
+	offset, err := parseHex(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("trailer offset: %w", err)
+	}
+
+	var key int64
+	for _, f := range fields[2:] {
+		k, err := parseHex(f)
+		if err != nil {
+			return nil, fmt.Errorf("key: %w", err)
+		}
+		key ^= k
+	}
+
+	return &Header{IndexOffset: offset, Key: key}, nil
+}
+
+// parseRPA2 parses "RPA-2.0 offset", which has no key.
+func parseRPA2(line string, _ io.ReaderAt, _ int64) (*Header, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return nil, errors.New("incomplete header")
+	}
+
+	offset, err := parseHex(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("trailer offset: %w", err)
+	}
+
+	return &Header{IndexOffset: offset}, nil
+}
+
+// parseRPA32 parses "RPA-3.2 offset unused key".
+func parseRPA32(line string, _ io.ReaderAt, _ int64) (*Header, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return nil, errors.New("incomplete header")
+	}
+
+	offset, err := parseHex(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("trailer offset: %w", err)
+	}
+	key, err := parseHex(fields[3])
+	if err != nil {
+		return nil, fmt.Errorf("key: %w", err)
+	}
+
+	return &Header{IndexOffset: offset, Key: key}, nil
+}
+
+// parseALT1 parses "ALT-1.0 key offset", where key is additionally xored with a constant.
+func parseALT1(line string, _ io.ReaderAt, _ int64) (*Header, error) {
+	const altKey = 0xdabe8df0
+
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return nil, errors.New("incomplete header")
+	}
+
+	key, err := parseHex(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("key: %w", err)
+	}
+	offset, err := parseHex(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("trailer offset: %w", err)
+	}
+
+	return &Header{IndexOffset: offset, Key: key ^ altKey}, nil
+}
+
+// parseZiX rejects ZiX archives. Their index offset and key are computed by obfuscated loader shipped
+// with the game, which is not implemented. Such archives can be read by registering a parser which
+// returns Header with the computed values.
+func parseZiX(line string, _ io.ReaderAt, _ int64) (*Header, error) {
+	magic, _, _ := strings.Cut(line, " ")
+	return nil, fmt.Errorf("%s archives are not supported, register a parser with the key from the game loader", magic)
+}
+
+func parseHex(s string) (int64, error) {
+	return strconv.ParseInt(s, 16, 64)
+}
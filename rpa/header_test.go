@@ -0,0 +1,103 @@
+package rpa
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseHeader(t *testing.T) {
+	for _, tc := range []struct {
+		line   string
+		offset int64
+		key    int64
+	}{
+		{"RPA-3.0 0000000000000100 00000001 00000002", 0x100, 3},
+		{"RPA-2.0 0000000000000100", 0x100, 0},
+		{"RPA-3.2 0000000000000100 ffff 00000042", 0x100, 0x42},
+		{"ALT-1.0 00000042 0000000000000100", 0x100, 0x42 ^ 0xdabe8df0},
+	} {
+		magic, _, _ := strings.Cut(tc.line, " ")
+		h, err := headers[magic](tc.line, nil, 0)
+		if err != nil {
+			t.Errorf("%s: %v", magic, err)
+			continue
+		}
+		if h.IndexOffset != tc.offset || h.Key != tc.key {
+			t.Errorf("%s: got offset %#x, key %#x, want %#x, %#x", magic, h.IndexOffset, h.Key, tc.offset, tc.key)
+		}
+	}
+
+	for _, line := range []string{
+		"RPA-3.0 0000000000000100",
+		"RPA-2.0",
+		"RPA-3.2 0000000000000100 ffff",
+		"ALT-1.0 zz 0000000000000100",
+		"ZiX-12A 0000000000000100 00000001",
+	} {
+		magic, _, _ := strings.Cut(line, " ")
+		if _, err := headers[magic](line, nil, 0); err == nil {
+			t.Errorf("%q: expected error", line)
+		}
+	}
+}
+
+func TestRegisterHeader(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetKey(0x42424242)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		err := w.Add(name, 5, func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("hello")), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Replace(buf.Bytes(), []byte("RPA-3.0 "), []byte("TST-1.0 "), 1)
+
+	// Test format reads the index through Index and skips the first byte of a.txt.
+	var paths []string
+	RegisterHeader("TST-1.0", func(line string, r io.ReaderAt, size int64) (*Header, error) {
+		h, err := parseRPA3(line, r, size)
+		if err != nil {
+			return nil, err
+		}
+		h.Index = io.NewSectionReader(r, h.IndexOffset, size-h.IndexOffset)
+		h.IndexOffset = -1
+		h.File = func(path string, offset, size int64) (int64, int64, error) {
+			paths = append(paths, path)
+			if path == "a.txt" {
+				return offset + 1, size - 1, nil
+			}
+			return offset, size, nil
+		}
+
+		return h, nil
+	})
+	defer delete(headers, "TST-1.0")
+
+	if !HasMagic(data) {
+		t.Error("registered magic is not recognized")
+	}
+	a, err := OpenArchive(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 {
+		t.Errorf("File called for %q", paths)
+	}
+	for name, want := range map[string]string{"a.txt": "ello", "b.txt": "hello"} {
+		got, err := a.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
@@ -7,16 +7,22 @@ import (
 	"io"
 	"math/big"
 	"path"
-	"strconv"
+	"slices"
 	"strings"
 
+	"github.com/kaey/gamearc/internal/arc"
 	pickle "github.com/kisielk/og-rek"
 )
 
 type Archive struct {
-	r     io.ReaderAt
-	size  int64
-	Files []File
+	*arc.FS
+
+	r    io.ReaderAt
+	size int64
+
+	// Version is the header magic, such as RPA-3.0.
+	Version string
+	Files   []File
 }
 
 type File struct {
@@ -24,14 +30,57 @@ type File struct {
 	path   string
 	offset int64
 	size   int64
+	key    int64
+	prefix []byte
 }
 
 func (f *File) Path() string {
 	return f.path
 }
 
+func (f *File) Offset() int64 {
+	return f.offset
+}
+
+// Size returns size of the file including prefix, which is the length stored in the index.
+func (f *File) Size() int64 {
+	return f.size
+}
+
+// Reader returns a reader of prefix followed by file data.
+// Prefix is counted in the size, so the rest of the file is stored in the archive.
 func (f *File) Reader() *io.SectionReader {
-	return io.NewSectionReader(f.r, f.offset, f.size)
+	if len(f.prefix) == 0 {
+		return io.NewSectionReader(f.r, f.offset, f.size)
+	}
+
+	data := io.NewSectionReader(f.r, f.offset, f.size-int64(len(f.prefix)))
+	return io.NewSectionReader(&prefixReaderAt{prefix: f.prefix, r: data}, 0, f.size)
+}
+
+func (f *File) Open() (io.ReadSeeker, error) {
+	return f.Reader(), nil
+}
+
+func (f *File) Meta() map[string]any {
+	return map[string]any{"key": f.key, "prefixSize": len(f.prefix)}
+}
+
+type prefixReaderAt struct {
+	prefix []byte
+	r      io.ReaderAt
+}
+
+func (r *prefixReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < int64(len(r.prefix)) {
+		n = copy(p, r.prefix[off:])
+		if n == len(p) {
+			return n, nil
+		}
+	}
+
+	m, err := r.r.ReadAt(p[n:], max(0, off-int64(len(r.prefix))))
+	return n + m, err
 }
 
 func OpenArchive(r io.ReaderAt, size int64) (*Archive, error) {
@@ -39,38 +88,60 @@ func OpenArchive(r io.ReaderAt, size int64) (*Archive, error) {
 	if err := a.readIndex(); err != nil {
 		return nil, fmt.Errorf("read index: %w", err)
 	}
+	a.FS = arc.NewFS(arc.Entries(a.Files))
 
 	return a, nil
 }
 
-func (a *Archive) readIndex() error {
-	var header [34]byte
-	if _, err := a.r.ReadAt(header[:], 0); err != nil {
-		return nil
+// OpenArchiveWithIndex opens RPA-1.0 archive, which has no header and keeps its index in a separate .rpi file.
+func OpenArchiveWithIndex(r io.ReaderAt, size int64, index io.Reader) (*Archive, error) {
+	a := &Archive{r: r, size: size, Version: "RPA-1.0"}
+	if err := a.decodeIndex(index, &Header{}); err != nil {
+		return nil, fmt.Errorf("read index: %w", err)
 	}
+	a.FS = arc.NewFS(arc.Entries(a.Files))
+
+	return a, nil
+}
 
-	if expected, got := []byte("RPA-3.0 "), header[0:8]; !bytes.Equal(expected, got) {
-		return fmt.Errorf("expected %q, got %q", expected, got)
+func (a *Archive) readIndex() error {
+	header := make([]byte, maxHeaderSize)
+	n, err := a.r.ReadAt(header, 0)
+	if err != nil && !(err == io.EOF && n > 0) {
+		return err
 	}
 
-	trailerOffset, err := strconv.ParseInt(string(header[8:24]), 16, 64)
-	if err != nil {
-		return fmt.Errorf("trailer offset: %w", err)
+	line, _, ok := bytes.Cut(header[:n], []byte("\n"))
+	if !ok {
+		return fmt.Errorf("incomplete header")
 	}
-	if trailerOffset >= a.size {
-		return fmt.Errorf("trailer offset beyond file size, offset: %v, size: %v", trailerOffset, a.size)
+	magic, _, _ := strings.Cut(string(line), " ")
+	parse := headers[magic]
+	if parse == nil {
+		return fmt.Errorf("unknown header %q", magic)
 	}
+	a.Version = magic
 
-	key, err := strconv.ParseInt(string(header[25:33]), 16, 64)
+	h, err := parse(string(line), a.r, a.size)
 	if err != nil {
-		return fmt.Errorf("key: %w", err)
+		return err
 	}
-	if header[33] != '\n' {
-		return fmt.Errorf("incomplete header")
+
+	index := h.Index
+	if index == nil {
+		if h.IndexOffset < 0 || h.IndexOffset >= a.size {
+			return fmt.Errorf("trailer offset beyond file size, offset: %v, size: %v", h.IndexOffset, a.size)
+		}
+		index = io.NewSectionReader(a.r, h.IndexOffset, a.size)
 	}
 
-	tr := io.NewSectionReader(a.r, trailerOffset, a.size)
-	tzr, err := zlib.NewReader(tr)
+	return a.decodeIndex(index, h)
+}
+
+// decodeIndex reads zlib compressed pickle of the index, offsets and sizes in it are decoded as h describes.
+func (a *Archive) decodeIndex(r io.Reader, h *Header) error {
+	key := h.Key
+	tzr, err := zlib.NewReader(r)
 	if err != nil {
 		return fmt.Errorf("trailer decompress: %w", err)
 	}
@@ -94,9 +165,11 @@ func (a *Archive) readIndex() error {
 		if !ok {
 			return fmt.Errorf("expected string path, got: %q", k)
 		}
-		// Strip first part (it matches archive name), clean then run checks.
-		pp := strings.Split(path.Clean(p), "/")
-		p = path.Join(pp[1:]...)
+		// Paths are relative to the game directory, clean then run checks.
+		p = path.Clean(p)
+		if p == "." {
+			return fmt.Errorf("archive contains a file with empty path")
+		}
 		if path.IsAbs(p) {
 			return fmt.Errorf("archive contains a file with absolute path: %q", p)
 		}
@@ -116,8 +189,9 @@ func (a *Archive) readIndex() error {
 		if !ok {
 			return fmt.Errorf("expected tuple value, got %T", v2)
 		}
-		if len(tuple) != 3 {
-			return fmt.Errorf("expected 3 items in a tuple, got %v", len(tuple))
+		// Old versions have no prefix in the tuple.
+		if len(tuple) != 2 && len(tuple) != 3 {
+			return fmt.Errorf("expected 2 or 3 items in a tuple, got %v", len(tuple))
 		}
 
 		offsetEnc, ok := tuple[0].(int64)
@@ -129,9 +203,6 @@ func (a *Archive) readIndex() error {
 			offsetEnc = o.Int64()
 		}
 		offset := offsetEnc ^ key
-		if offset >= a.size {
-			return fmt.Errorf("file offset beyond file size, offset: %v, size: %v", offset, a.size)
-		}
 
 		size, ok := tuple[1].(int64)
 		if !ok {
@@ -139,13 +210,67 @@ func (a *Archive) readIndex() error {
 		}
 		size ^= key
 
+		if h.File != nil {
+			if offset, size, err = h.File(p, offset, size); err != nil {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+		}
+		if offset >= a.size {
+			return fmt.Errorf("file offset beyond file size, offset: %v, size: %v", offset, a.size)
+		}
+
+		// Prefix is prepended to file data, usually it is empty.
+		var prefix []byte
+		if len(tuple) == 3 {
+			if prefix, err = pickleBytes(tuple[2]); err != nil {
+				return fmt.Errorf("prefix: %w", err)
+			}
+		}
+		if int64(len(prefix)) > size {
+			return fmt.Errorf("prefix longer than file size, path: %q, prefix: %v, size: %v", p, len(prefix), size)
+		}
+
 		a.Files = append(a.Files, File{
 			r:      a.r,
 			path:   p,
 			offset: offset,
 			size:   size,
+			key:    key,
+			prefix: prefix,
 		})
 	}
 
+	// Index is a dict, which order is lost when unpickled.
+	slices.SortFunc(a.Files, func(a, b File) int { return strings.Compare(a.path, b.path) })
+
 	return nil
 }
+
+// pickleBytes converts python 2 str or python 3 bytes to []byte.
+// Python 3 pickles bytes with protocol 2 as a call to bytes() or _codecs.encode(str, "latin1").
+func pickleBytes(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), nil
+	case pickle.Bytes:
+		return []byte(v), nil
+	case pickle.Call:
+		switch {
+		case v.Callable.Name == "bytes" && len(v.Args) == 0:
+			return nil, nil
+		case v.Callable == pickle.Class{Module: "_codecs", Name: "encode"} && len(v.Args) == 2 && v.Args[1] == "latin1":
+			s, ok := v.Args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("expected string argument, got %T", v.Args[0])
+			}
+			b := make([]byte, 0, len(s))
+			for _, r := range s {
+				b = append(b, byte(r))
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("unexpected call %s.%s", v.Callable.Module, v.Callable.Name)
+	default:
+		return nil, fmt.Errorf("expected string or bytes value, got %T", v)
+	}
+}
@@ -0,0 +1,127 @@
+package rpa
+
+import (
+	"bufio"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+
+	pickle "github.com/kisielk/og-rek"
+)
+
+// Writer creates RPA-3.0 archives.
+// Files are collected with Add or AddFS and written out by Close,
+// because header contains offset of the index which follows file data.
+type Writer struct {
+	w     io.Writer
+	key   uint32
+	files []writerFile
+}
+
+type writerFile struct {
+	path string
+	size int64
+	open func() (io.ReadCloser, error)
+}
+
+// NewWriter returns a Writer with random key.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, key: rand.Uint32()}
+}
+
+// SetKey sets the key used to obfuscate offsets and sizes in the index.
+func (w *Writer) SetKey(key uint32) {
+	w.key = key
+}
+
+// Add adds a file. Name must be relative to the game directory, as Ren'Py expects.
+// Open is called once when the archive is written and must return exactly size bytes.
+func (w *Writer) Add(name string, size int64, open func() (io.ReadCloser, error)) error {
+	if !fs.ValidPath(name) || name == "." {
+		return fmt.Errorf("bad path: %q", name)
+	}
+	if size < 0 {
+		return fmt.Errorf("%s: bad size %d", name, size)
+	}
+
+	w.files = append(w.files, writerFile{
+		path: name,
+		size: size,
+		open: open,
+	})
+
+	return nil
+}
+
+// AddFS adds all regular files from fsys.
+func (w *Writer) AddFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		return w.Add(name, info.Size(), func() (io.ReadCloser, error) {
+			return fsys.Open(name)
+		})
+	})
+}
+
+// Close writes the archive. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	const headerSize = 34
+	key := int64(w.key)
+
+	index := make(map[interface{}]interface{}, len(w.files))
+	offset := int64(headerSize)
+	for _, f := range w.files {
+		index[f.path] = []interface{}{pickle.Tuple{offset ^ key, f.size ^ key, pickle.Bytes("")}}
+		offset += f.size
+	}
+
+	bw := bufio.NewWriter(w.w)
+	fmt.Fprintf(bw, "RPA-3.0 %016x %08x\n", offset, w.key)
+
+	for _, f := range w.files {
+		if err := writeFile(bw, f); err != nil {
+			return fmt.Errorf("%s: %w", f.path, err)
+		}
+	}
+
+	zw := zlib.NewWriter(bw)
+	if err := pickle.NewEncoder(zw).Encode(index); err != nil {
+		return fmt.Errorf("index encode: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+func writeFile(w io.Writer, f writerFile) error {
+	r, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	n, err := io.Copy(w, io.LimitReader(r, f.size))
+	if err != nil {
+		return err
+	}
+	if n != f.size {
+		return fmt.Errorf("expected %d bytes, got %d", f.size, n)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package rpa
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	fsys := fstest.MapFS{
+		"script.rpy":    {Data: []byte("label start:\n    \"Hello.\"\n")},
+		"images/bg.png": {Data: []byte{0x89, 'P', 'N', 'G'}},
+		"a/b/c.txt":     {Data: []byte(strings.Repeat("c", 1000))},
+		"empty.txt":     {Data: nil},
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetKey(0x42424242)
+	if err := w.AddFS(fsys); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := OpenArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Version != "RPA-3.0" {
+		t.Errorf("version: got %q, want RPA-3.0", a.Version)
+	}
+	if len(a.Files) != len(fsys) {
+		t.Fatalf("got %d files, want %d", len(a.Files), len(fsys))
+	}
+	for _, f := range a.Files {
+		want, ok := fsys[f.Path()]
+		if !ok {
+			t.Errorf("unexpected path %q", f.Path())
+			continue
+		}
+		if f.Meta()["key"] != int64(0x42424242) {
+			t.Errorf("%s: got key %v", f.Path(), f.Meta()["key"])
+		}
+		data, err := io.ReadAll(f.Reader())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, want.Data) {
+			t.Errorf("%s: got %q, want %q", f.Path(), data, want.Data)
+		}
+	}
+
+	// Files are reachable through fs.FS as well.
+	for name, want := range fsys {
+		data, err := a.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, want.Data) {
+			t.Errorf("%s: got %q, want %q", name, data, want.Data)
+		}
+	}
+}
+
+func TestWriterBadPath(t *testing.T) {
+	w := NewWriter(io.Discard)
+	for _, name := range []string{"", ".", "/abs", "../out", "a//b"} {
+		if err := w.Add(name, 0, nil); err == nil {
+			t.Errorf("%q: expected error", name)
+		}
+	}
+}
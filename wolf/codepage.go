@@ -0,0 +1,29 @@
+package wolf
+
+import (
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// https://docs.microsoft.com/en-us/windows/win32/intl/code-page-identifiers
+var codepages = map[int]encoding.Encoding{
+	874:   charmap.Windows874,
+	932:   japanese.ShiftJIS,
+	936:   simplifiedchinese.GBK,
+	949:   korean.EUCKR, // Actually implements its superset, cp949.
+	950:   traditionalchinese.Big5,
+	1250:  charmap.Windows1250,
+	1251:  charmap.Windows1251,
+	1252:  charmap.Windows1252,
+	65001: unicode.UTF8,
+}
+
+// Encoding returns encoding of file names for Windows codepage, nil if codepage is not supported.
+func Encoding(codepage int) encoding.Encoding {
+	return codepages[codepage]
+}
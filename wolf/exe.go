@@ -0,0 +1,122 @@
+package wolf
+
+import (
+	"bytes"
+	"debug/pe"
+	"fmt"
+	"io"
+)
+
+// FindKeys scans sections of PE executable exe for keys which decrypt the archive.
+// Every 12 bytes sequence is tried as a raw key and every printable string as a password.
+// Returned options can be passed to OpenArchiveWithOptions.
+func FindKeys(exe io.ReaderAt, archive io.ReaderAt, size int64) ([]Options, error) {
+	raw := make([]byte, 64)
+	if n, err := archive.ReadAt(raw, 0); err != nil && !(err == io.EOF && n >= 28) {
+		return nil, err
+	}
+
+	pf, err := pe.NewFile(exe)
+	if err != nil {
+		return nil, fmt.Errorf("exe: %w", err)
+	}
+	defer pf.Close()
+
+	v8 := raw[0] == 'D' && raw[1] == 'X' && getUint16(raw, 2) == 8
+	if v8 && getUint32(raw, 44)&flagNoKey != 0 {
+		return nil, fmt.Errorf("archive is not encrypted")
+	}
+
+	var res []Options
+	seen := make(map[string]bool)
+	try := func(opts Options) {
+		id := string(opts.Key) + "\x00" + opts.Password
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		if checkKey(archive, size, raw, &opts) {
+			res = append(res, opts)
+		}
+	}
+
+	for _, s := range pf.Sections {
+		data, err := s.Data()
+		if err != nil {
+			// Uninitialized sections have no data.
+			continue
+		}
+
+		if !v8 {
+			for i := 0; i+12 <= len(data); i++ {
+				// Cheap check of the first two bytes before doing full one.
+				if data[i]^raw[0] != 'D' || data[i+1]^raw[1] != 'X' {
+					continue
+				}
+				try(Options{Key: bytes.Clone(data[i : i+12])})
+			}
+		}
+
+		for _, str := range printableStrings(data) {
+			try(Options{Password: str})
+		}
+	}
+
+	return res, nil
+}
+
+// checkKey reports whether archive header decrypted with opts is valid and its root directory has no parent.
+func checkKey(r io.ReaderAt, size int64, raw []byte, opts *Options) bool {
+	if raw[0] == 'D' && raw[1] == 'X' && getUint16(raw, 2) == 8 {
+		h, err := parseHeader8(raw, opts)
+		if err != nil {
+			return false
+		}
+
+		// Header may be compressed, so tables are read in full.
+		_, err = readTables(r, size, h)
+		return err == nil
+	}
+
+	key := opts.Key
+	if key == nil {
+		key = keyCreate6([]byte(opts.Password))
+	}
+	h, err := decryptHeader(raw, key)
+	if err != nil {
+		return false
+	}
+
+	w := h.layout.word
+	pos := h.dirTableOffset + w
+	if pos < 0 || pos+w > h.headSize {
+		return false
+	}
+
+	b := make([]byte, w)
+	if _, err := r.ReadAt(b, int64(h.nameTableOffset+pos)); err != nil {
+		return false
+	}
+	xor(b, h.key, pos)
+
+	return bytes.Count(b, []byte{0xff}) == w
+}
+
+// printableStrings returns zero-terminated ascii strings which may be used as passwords.
+func printableStrings(data []byte) []string {
+	const minLen, maxLen = 4, 63
+
+	var res []string
+	start := 0
+	for i, c := range data {
+		if c >= 0x21 && c <= 0x7e {
+			continue
+		}
+		if c == 0 && i-start >= minLen && i-start <= maxLen {
+			res = append(res, string(data[start:i]))
+		}
+		start = i + 1
+	}
+
+	return res
+}
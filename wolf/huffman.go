@@ -0,0 +1,142 @@
+package wolf
+
+import "fmt"
+
+// huffmanNode is a node of huffman tree, leaves are the first 256 nodes.
+type huffmanNode struct {
+	weight   int
+	parent   int
+	children [2]int
+}
+
+// huffmanTree builds DxLib huffman tree from byte weights.
+// Two parentless nodes with the lowest weight are joined until one is left, ties are resolved by lower index.
+// Last node is the root.
+func huffmanTree(weights *[256]int) []huffmanNode {
+	nodes := make([]huffmanNode, 256, 256+255)
+	for i := range nodes {
+		nodes[i] = huffmanNode{weight: weights[i], parent: -1, children: [2]int{-1, -1}}
+	}
+
+	for n := 256; n > 1; n-- {
+		min1, min2 := -1, -1
+		for i := range nodes {
+			if nodes[i].parent != -1 {
+				continue
+			}
+			switch {
+			case min1 == -1 || nodes[min1].weight > nodes[i].weight:
+				min1, min2 = i, min1
+			case min2 == -1 || nodes[min2].weight > nodes[i].weight:
+				min2 = i
+			}
+		}
+
+		nodes[min1].parent = len(nodes)
+		nodes[min2].parent = len(nodes)
+		nodes = append(nodes, huffmanNode{
+			weight:   nodes[min1].weight + nodes[min2].weight,
+			parent:   -1,
+			children: [2]int{min1, min2},
+		})
+	}
+
+	return nodes
+}
+
+// bitReader reads bits from the most significant one.
+type bitReader struct {
+	b   []byte
+	pos int // in bits
+}
+
+func (r *bitReader) read(n int) (uint64, error) {
+	if r.pos+n > len(r.b)*8 {
+		return 0, errCorrupt
+	}
+
+	var v uint64
+	for range n {
+		v = v<<1 | uint64(r.b[r.pos/8]>>(7-r.pos%8)&1)
+		r.pos++
+	}
+
+	return v, nil
+}
+
+// huffmanDecode decompresses DxLib huffman data.
+//
+// Data starts with a bit stream of original and compressed sizes, each prefixed by its width in 6 bits,
+// and 256 byte weights as differences from the previous weight, each prefixed by 3 bits of width
+// in pairs of bits and a sign bit. Codes follow from the next byte boundary.
+func huffmanDecode(src []byte) ([]byte, error) {
+	br := &bitReader{b: src}
+	var sizes [2]uint64
+	for i := range sizes {
+		n, err := br.read(6)
+		if err != nil {
+			return nil, err
+		}
+		if sizes[i], err = br.read(int(n) + 1); err != nil {
+			return nil, err
+		}
+	}
+	origSize, pressSize := sizes[0], sizes[1]
+	// Checked early, since most of wrong keys fail here.
+	if pressSize > uint64(len(src)) {
+		return nil, fmt.Errorf("%w: compressed size %d beyond input %d", errCorrupt, pressSize, len(src))
+	}
+
+	var weights [256]int
+	for i := range weights {
+		n, err := br.read(3)
+		if err != nil {
+			return nil, err
+		}
+		minus, err := br.read(1)
+		if err != nil {
+			return nil, err
+		}
+		v, err := br.read(int(n+1) * 2)
+		if err != nil {
+			return nil, err
+		}
+
+		// Weights are 16-bit, differences wrap around.
+		if minus == 1 {
+			v = -v
+		}
+		if i > 0 {
+			v += uint64(weights[i-1])
+		}
+		weights[i] = int(uint16(v))
+	}
+
+	codes := src[(br.pos+7)/8:]
+	if pressSize > uint64(len(codes)) {
+		return nil, fmt.Errorf("%w: compressed size %d beyond input %d", errCorrupt, pressSize, len(codes))
+	}
+	codes = codes[:pressSize]
+	// Every code is at least one bit long.
+	if origSize > uint64(len(codes))*8 {
+		return nil, fmt.Errorf("%w: size %d is too large for %d bytes", errCorrupt, origSize, len(codes))
+	}
+
+	nodes := huffmanTree(&weights)
+	root := len(nodes) - 1
+	dst := make([]byte, 0, origSize)
+	br = &bitReader{b: codes}
+	for uint64(len(dst)) < origSize {
+		n := root
+		for n >= 256 {
+			bit, err := br.read(1)
+			if err != nil {
+				return nil, err
+			}
+			n = nodes[n].children[bit]
+		}
+		dst = append(dst, byte(n))
+	}
+
+	return dst, nil
+}
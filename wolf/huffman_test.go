@@ -0,0 +1,170 @@
+package wolf
+
+import (
+	"bytes"
+	"io"
+	"math/bits"
+	"strings"
+	"testing"
+)
+
+type bitWriter struct {
+	b   []byte
+	pos int
+}
+
+func (w *bitWriter) write(n int, v uint64) {
+	for i := n - 1; i >= 0; i-- {
+		if w.pos%8 == 0 {
+			w.b = append(w.b, 0)
+		}
+		w.b[len(w.b)-1] |= byte(v>>i&1) << (7 - w.pos%8)
+		w.pos++
+	}
+}
+
+// writeSize writes width of v in 6 bits followed by v.
+func (w *bitWriter) writeSize(v uint64) {
+	n := max(bits.Len64(v), 1)
+	w.write(6, uint64(n-1))
+	w.write(n, v)
+}
+
+// huffmanEncode is DxLib huffman encoder, see huffmanDecode for the format.
+func huffmanEncode(src []byte) []byte {
+	var counts [256]int
+	for _, c := range src {
+		counts[c]++
+	}
+	var weights [256]int
+	for i, c := range counts {
+		// Weights are 16-bit, large counts are scaled down but kept above zero.
+		weights[i] = c
+		if len(src) > 0xffff {
+			weights[i] = c * 0xffff / len(src)
+			if c > 0 && weights[i] == 0 {
+				weights[i] = 1
+			}
+		}
+	}
+
+	nodes := huffmanTree(&weights)
+	var codes [256][]int
+	for i := range codes {
+		for n := i; nodes[n].parent != -1; n = nodes[n].parent {
+			p := nodes[n].parent
+			bit := 0
+			if nodes[p].children[1] == n {
+				bit = 1
+			}
+			codes[i] = append([]int{bit}, codes[i]...)
+		}
+	}
+
+	data := new(bitWriter)
+	for _, c := range src {
+		for _, bit := range codes[c] {
+			data.write(1, uint64(bit))
+		}
+	}
+
+	head := new(bitWriter)
+	head.writeSize(uint64(len(src)))
+	head.writeSize(uint64(len(data.b)))
+	prev := 0
+	for _, w := range weights {
+		diff := w - prev
+		prev = w
+		minus := uint64(0)
+		if diff < 0 {
+			minus, diff = 1, -diff
+		}
+		n := max((bits.Len(uint(diff))+1)/2, 1)
+		head.write(3, uint64(n-1))
+		head.write(1, minus)
+		head.write(n*2, uint64(diff))
+	}
+
+	return append(head.b, data.b...)
+}
+
+func TestHuffman(t *testing.T) {
+	for _, src := range [][]byte{
+		nil,
+		[]byte("a"),
+		[]byte("abracadabra"),
+		bytes.Repeat([]byte{0, 1, 2, 3, 0, 0, 0, 255}, 20000),
+		literals(1000),
+	} {
+		got, err := huffmanDecode(huffmanEncode(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, src) {
+			t.Errorf("round trip of %d bytes differs", len(src))
+		}
+	}
+}
+
+func TestHuffmanErrors(t *testing.T) {
+	valid := huffmanEncode([]byte("abracadabra"))
+	for _, src := range [][]byte{
+		nil,
+		valid[:10],
+		valid[:len(valid)-1],
+	} {
+		if _, err := huffmanDecode(src); err == nil {
+			t.Errorf("%x: expected error", src)
+		}
+	}
+}
+
+// TestCompressedHeader reads version 8 archive with header compressed the way DxLib does by default:
+// LZ, huffman, then encrypted with the archive key.
+func TestCompressedHeader(t *testing.T) {
+	for _, password := range []string{"", "secret"} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		w.SetVersion(8)
+		w.SetOptions(Options{Password: password})
+		for _, name := range []string{"a.txt", "Data/b.txt", "Data/c/d.txt"} {
+			err := w.AddFile(FileHeader{Name: name, Size: int64(len(name))}, func() (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(name)), nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		b := buf.Bytes()
+		flags := getUint32(b, 44)
+		key := keyCreate8([]byte(password))
+		if flags&flagNoKey != 0 {
+			key = nil
+		}
+		nameTableOffset := getUint64(b, 16)
+		trailer := b[nameTableOffset:]
+		xor(trailer, key, 0)
+		press := huffmanEncode(encode(trailer))
+		xor(press, key, 0)
+		b = append(b[:nameTableOffset:nameTableOffset], press...)
+		putUint32(b[44:48], flags&^flagNoHeadPress)
+
+		a, err := OpenArchiveWithOptions(bytes.NewReader(b), int64(len(b)), Options{Password: password})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"a.txt", "Data/b.txt", "Data/c/d.txt"} {
+			data, err := a.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != name {
+				t.Errorf("%s: got %q", name, data)
+			}
+		}
+	}
+}
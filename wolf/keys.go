@@ -0,0 +1,82 @@
+package wolf
+
+import (
+	"hash/crc32"
+	"slices"
+)
+
+// Key is a known key of version 5, 6 or 7 archives.
+type Key struct {
+	Name string
+	Key  []byte
+}
+
+// Keys are tried in order when key derived from the header does not work.
+var Keys = []Key{
+	{Name: "DxLib default", Key: keyCreate6(nil)},
+	{Name: "Wolf RPG Editor", Key: []byte{0x0f, 0x53, 0xe1, 0x3e, 0x04, 0x37, 0x12, 0x17, 0x60, 0x0f, 0x53, 0xe1}},
+}
+
+// Password is a known password of version 8 archives.
+type Password struct {
+	Name     string
+	Password string
+}
+
+// Passwords are tried in order for encrypted version 8 archives when Options.Password does not work.
+// Only DxLib default is listed, passwords of particular games can be found with FindKeys.
+var Passwords = []Password{
+	{Name: "DxLib default", Password: ""},
+}
+
+// keyCreate6 creates 12 bytes key of versions 5 to 7 from a password.
+func keyCreate6(password []byte) []byte {
+	key := make([]byte, 12)
+	if len(password) == 0 {
+		for i := range key {
+			key[i] = 0xaa
+		}
+	} else {
+		for i := range key {
+			key[i] = password[i%len(password)]
+		}
+	}
+
+	key[0] = ^key[0]
+	key[1] = key[1]>>4 | key[1]<<4
+	key[2] ^= 0x8a
+	key[3] = ^(key[3]>>4 | key[3]<<4)
+	key[4] = ^key[4]
+	key[5] ^= 0xac
+	key[6] = ^key[6]
+	key[7] = ^(key[7]>>3 | key[7]<<5)
+	key[8] = key[8]>>5 | key[8]<<3
+	key[9] ^= 0x7f
+	key[10] = (key[10]>>4 | key[10]<<4) ^ 0xd6
+	key[11] ^= 0xcc
+
+	return key
+}
+
+// keyCreate8 creates version 8 key from a key string, which is a password
+// for the archive key or a password followed by file and directory names for file keys.
+func keyCreate8(s []byte) []byte {
+	if len(s) < 4 {
+		s = append(slices.Clone(s), "DXARC"...)
+	}
+
+	var even, odd []byte
+	for i, c := range s {
+		if i%2 == 0 {
+			even = append(even, c)
+		} else {
+			odd = append(odd, c)
+		}
+	}
+
+	var key [8]byte
+	putUint32(key[0:4], int(crc32.ChecksumIEEE(even)))
+	putUint32(key[4:8], int(crc32.ChecksumIEEE(odd)))
+
+	return key[:7]
+}
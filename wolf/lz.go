@@ -0,0 +1,198 @@
+package wolf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// minCompress is the shortest match length, stored lengths are reduced by it.
+const minCompress = 4
+
+var errCorrupt = errors.New("corrupt compressed data")
+
+// decode decompresses DxLib LZ data.
+//
+// Data starts with 9 bytes header: uint32 decompressed size, uint32 compressed size including header
+// and a key byte. Any byte not equal to the key is a literal, key followed by key is a literal key byte,
+// otherwise key is followed by a code byte, optional extra length byte and 1-3 bytes of backwards distance.
+func decode(src []byte) ([]byte, error) {
+	if len(src) < 9 {
+		return nil, errCorrupt
+	}
+
+	destSize := getUint32(src, 0)
+	srcSize := getUint32(src, 4)
+	key := src[8]
+	if srcSize < 9 || srcSize > len(src) {
+		return nil, fmt.Errorf("%w: bad size %d", errCorrupt, srcSize)
+	}
+
+	// Size is not trusted, output grows past capacity only if input really expands that much.
+	dst := make([]byte, 0, min(destSize, 16*srcSize))
+	sp := src[9:srcSize]
+	for len(sp) > 0 {
+		if sp[0] != key {
+			dst = append(dst, sp[0])
+			sp = sp[1:]
+			continue
+		}
+
+		if len(sp) < 2 {
+			return nil, errCorrupt
+		}
+		if sp[1] == key {
+			dst = append(dst, key)
+			sp = sp[2:]
+			continue
+		}
+
+		// Codes above key were incremented to avoid clashing with it.
+		code := int(sp[1])
+		if code > int(key) {
+			code--
+		}
+		sp = sp[2:]
+
+		length := code >> 3
+		if code&0x4 != 0 {
+			if len(sp) < 1 {
+				return nil, errCorrupt
+			}
+			length |= int(sp[0]) << 5
+			sp = sp[1:]
+		}
+		length += minCompress
+
+		n := code&0x3 + 1
+		if n > 3 || len(sp) < n {
+			return nil, errCorrupt
+		}
+		dist := 0
+		for i := range n {
+			dist |= int(sp[i]) << (8 * i)
+		}
+		dist++
+		sp = sp[n:]
+
+		if dist > len(dst) {
+			return nil, fmt.Errorf("%w: distance %d beyond output %d", errCorrupt, dist, len(dst))
+		}
+
+		// Source and destination may overlap, copy byte by byte.
+		start := len(dst) - dist
+		for i := range length {
+			dst = append(dst, dst[start+i])
+		}
+	}
+
+	if len(dst) != destSize {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", errCorrupt, destSize, len(dst))
+	}
+
+	return dst, nil
+}
+
+// encode compresses src with DxLib LZ, see decode for the format.
+// Least frequent byte is chosen as the key to minimize escaping of literal key bytes.
+func encode(src []byte) []byte {
+	const (
+		hashBits  = 16
+		maxLength = 0x1fff + minCompress
+		maxDist   = 1 << 24
+		maxChain  = 64
+	)
+
+	var freq [256]int
+	for _, c := range src {
+		freq[c]++
+	}
+	key := byte(0)
+	for i := range freq {
+		if freq[i] < freq[key] {
+			key = byte(i)
+		}
+	}
+
+	// Hash chains of positions with equal first minCompress bytes, stored as position+1.
+	head := make([]int32, 1<<hashBits)
+	prev := make([]int32, len(src))
+	hash := func(i int) int {
+		return int(uint32(getUint32(src, i))*2654435761) >> (32 - hashBits)
+	}
+	insert := func(i int) {
+		if i+minCompress <= len(src) {
+			h := hash(i)
+			prev[i] = head[h]
+			head[h] = int32(i + 1)
+		}
+	}
+
+	dst := make([]byte, 9, 9+len(src)/2)
+	for i := 0; i < len(src); {
+		length, dist := 0, 0
+		if i+minCompress <= len(src) {
+			for j, n := int(head[hash(i)])-1, 0; j >= 0 && i-j <= maxDist && n < maxChain; j, n = int(prev[j])-1, n+1 {
+				l := 0
+				for l < maxLength && i+l < len(src) && src[j+l] == src[i+l] {
+					l++
+				}
+				if l > length {
+					length, dist = l, i-j
+				}
+			}
+		}
+
+		if length < minCompress {
+			dst = append(dst, src[i])
+			if src[i] == key {
+				dst = append(dst, key)
+			}
+			insert(i)
+			i++
+			continue
+		}
+
+		dst = appendMatch(dst, key, length, dist)
+		for end := i + length; i < end; i++ {
+			insert(i)
+		}
+	}
+
+	putUint32(dst[0:4], len(src))
+	putUint32(dst[4:8], len(dst))
+	dst[8] = key
+
+	return dst
+}
+
+// appendMatch appends key, code byte, optional extra length byte and distance of a match.
+func appendMatch(dst []byte, key byte, length, dist int) []byte {
+	l := length - minCompress
+	d := dist - 1
+	n := 1
+	if d >= 1<<8 {
+		n = 2
+	}
+	if d >= 1<<16 {
+		n = 3
+	}
+
+	code := (l&0x1f)<<3 | (n - 1)
+	if l > 0x1f {
+		code |= 0x4
+	}
+	// Highest code is 0xfe, so incremented one still fits a byte.
+	if code >= int(key) {
+		code++
+	}
+
+	dst = append(dst, key, byte(code))
+	if l > 0x1f {
+		dst = append(dst, byte(l>>5))
+	}
+	for i := range n {
+		dst = append(dst, byte(d>>(8*i)))
+	}
+
+	return dst
+}
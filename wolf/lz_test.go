@@ -0,0 +1,113 @@
+package wolf
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// lzHeader returns DxLib LZ header followed by body.
+func lzHeader(destSize int, key byte, body ...byte) []byte {
+	b := make([]byte, 9, 9+len(body))
+	putUint32(b[0:4], destSize)
+	putUint32(b[4:8], 9+len(body))
+	b[8] = key
+
+	return append(b, body...)
+}
+
+// literals returns n bytes which are never equal to 0xff key.
+func literals(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 255)
+	}
+
+	return b
+}
+
+// TestDecode decodes streams assembled by hand following DxLib DXA_Decode,
+// independently of encode, to cover every code form.
+func TestDecode(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  []byte
+		want []byte
+	}{
+		{"empty", lzHeader(0, 0xff), nil},
+		{"literals", lzHeader(3, 0xff, 'a', 'b', 'c'), []byte("abc")},
+		{"escaped key", lzHeader(3, 'a', 'x', 'a', 'a', 'y'), []byte("xay")},
+		// Length 6 is code 2<<3, distance 2 is stored as 1 in one byte.
+		{"overlapping match", lzHeader(8, 0xff, 'a', 'b', 0xff, 0x10, 0x01), []byte("abababab")},
+		// Code 0x10 is above key 0x05, so it is stored incremented.
+		{"code above key", lzHeader(8, 0x05, 'a', 'b', 0x05, 0x11, 0x01), []byte("abababab")},
+		// Length 100 is 96 over minimum: low 5 bits in code with 0x4 flag, the rest in extra byte.
+		{"extra length", lzHeader(101, 0xff, 'x', 0xff, 0x04, 0x03, 0x00), bytes.Repeat([]byte("x"), 101)},
+		{
+			"two byte distance",
+			lzHeader(304, 0xff, append(literals(300), 0xff, 0x01, 0x2b, 0x01)...),
+			append(literals(300), 0, 1, 2, 3),
+		},
+		{
+			"three byte distance",
+			lzHeader(70004, 0xff, append(literals(70000), 0xff, 0x02, 0x6f, 0x11, 0x01)...),
+			append(literals(70000), 0, 1, 2, 3),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := decode(tc.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  []byte
+	}{
+		{"short header", []byte{1, 0, 0, 0, 9, 0}},
+		{"size beyond input", lzHeader(1, 0xff, 'a')[:9]},
+		{"distance beyond output", lzHeader(5, 0xff, 'a', 0xff, 0x00, 0x01)},
+		{"four byte distance", lzHeader(5, 0xff, 'a', 0xff, 0x03, 0, 0, 0, 0)},
+		{"truncated code", lzHeader(1, 0xff, 0xff)},
+		{"truncated distance", lzHeader(5, 0xff, 'a', 0xff, 0x01, 0x00)},
+		{"size mismatch", lzHeader(4, 0xff, 'a', 'b', 'c')},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := decode(tc.src); !errors.Is(err, errCorrupt) {
+				t.Errorf("got %v, want %v", err, errCorrupt)
+			}
+		})
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, src := range [][]byte{
+		nil,
+		[]byte("a"),
+		bytes.Repeat([]byte("abc"), 5000),
+		append(literals(70000), literals(70000)...),
+		bytes.Repeat([]byte{0, 0xff}, 100),
+	} {
+		got, err := decode(encode(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, src) {
+			t.Errorf("round trip of %d bytes differs", len(src))
+		}
+	}
+}
+
+func TestOpenHuffman(t *testing.T) {
+	f := &File{huffmanSize: 10, compressedSize: -1}
+	if _, err := f.Open(); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("got %v, want %v", err, errors.ErrUnsupported)
+	}
+}
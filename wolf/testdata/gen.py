@@ -0,0 +1,200 @@
+# Generates dxa archives used by wolf tests: python3 gen.py
+#
+# vN.dxa are read by reader tests, they use a custom key, Shift-JIS names and LZ compression.
+# writerN.dxa are the archives Writer is expected to produce byte for byte.
+import struct
+import zlib
+
+FILETIME = 132000000000000000  # 2019-04-17 18:40:00 UTC
+
+
+def key_create6(password):
+    # DxLib DXA_KeyCreate for versions 5 to 7.
+    key = bytearray(12)
+    for i in range(12):
+        key[i] = password[i % len(password)] if password else 0xAA
+
+    def rotr(c, n):
+        return ((c >> n) | (c << (8 - n))) & 0xFF
+
+    key[0] ^= 0xFF
+    key[1] = rotr(key[1], 4)
+    key[2] ^= 0x8A
+    key[3] = rotr(key[3], 4) ^ 0xFF
+    key[4] ^= 0xFF
+    key[5] ^= 0xAC
+    key[6] ^= 0xFF
+    key[7] = rotr(key[7], 3) ^ 0xFF
+    key[8] = rotr(key[8], 5)
+    key[9] ^= 0x7F
+    key[10] = rotr(key[10], 4) ^ 0xD6
+    key[11] ^= 0xCC
+    return bytes(key)
+
+
+def key_create8(s):
+    # DxLib DXA_KeyCreate for version 8: crc32 of even and odd bytes, first 7 bytes are used.
+    if len(s) < 4:
+        s += b"DXARC"
+    return struct.pack("<II", zlib.crc32(s[0::2]), zlib.crc32(s[1::2]))[:7]
+
+
+def xor(data, key, pos=0):
+    if not key:
+        return bytes(data)
+    return bytes(b ^ key[(pos + i) % len(key)] for i, b in enumerate(data))
+
+
+def lz(data, key=0xAB):
+    # Greedy DxLib LZ encoder: key, code byte, optional extra length byte, 1-3 bytes of distance.
+    out = bytearray()
+    i = 0
+    while i < len(data):
+        length, dist = 0, 0
+        for j in range(max(0, i - 0xFFFF), i):
+            n = 0
+            while i + n < len(data) and data[j + n] == data[i + n] and n < 0x1FFF + 4:
+                n += 1
+            if n > length:
+                length, dist = n, i - j
+        if length >= 4:
+            c = length - 4
+            d = dist - 1
+            nb = 1 if d < 0x100 else 2 if d < 0x10000 else 3
+            code = (c & 0x1F) << 3 | (4 if c > 0x1F else 0) | (nb - 1)
+            if code >= key:
+                code += 1
+            out += bytes([key, code])
+            if c > 0x1F:
+                out.append(c >> 5)
+            out += d.to_bytes(nb, "little")
+            i += length
+        else:
+            out += bytes([data[i], data[i]]) if data[i] == key else bytes([data[i]])
+            i += 1
+    return struct.pack("<IIB", len(data), len(out) + 9, key) + bytes(out)
+
+
+def upper(b):
+    # Ascii letters are uppercased, trail bytes of double-byte characters are kept as is.
+    out = bytearray(b)
+    i = 0
+    while i < len(out):
+        if 0x81 <= out[i] <= 0x9F or 0xE0 <= out[i] <= 0xFC:
+            i += 2
+            continue
+        if ord("a") <= out[i] <= ord("z"):
+            out[i] -= 0x20
+        i += 1
+    return bytes(out)
+
+
+def name_entry(name):
+    b = name.encode("shift_jis")
+    n = (len(b) + 4) // 4 * 4
+    up = upper(b)
+    return struct.pack("<HH", n // 4, sum(up) & 0xFFFF) + up.ljust(n, b"\0") + b.ljust(n, b"\0")
+
+
+def build(version, files, key=None, password=b"", times=(FILETIME, FILETIME + 1, FILETIME + 2), dir_times=None):
+    """files are (path, data, attr, compress) tuples."""
+    word = 4 if version == 5 else 8
+    file_size = {5: 44, 8: 72}.get(version, 64)
+    dir_size = 16 if version == 5 else 32
+    if dir_times is None:
+        dir_times = times
+
+    def file_entry(name_offset, attr, ts, data_offset, size, compressed_size):
+        w = "I" if word == 4 else "Q"
+        b = struct.pack("<" + w + w + "QQQ", name_offset, attr, *ts)
+        b += struct.pack("<" + w + w, data_offset, size)
+        b += struct.pack("<i" if word == 4 else "<q", compressed_size)
+        if version == 8:
+            b += struct.pack("<q", -1)
+        return b
+
+    def dir_entry(file_offset, parent_offset, n, list_offset):
+        if word == 4:
+            return struct.pack("<IIII", file_offset, parent_offset & 0xFFFFFFFF, n, list_offset)
+        return struct.pack("<QQQQ", file_offset, parent_offset & (2**64 - 1), n, list_offset)
+
+    tree = {}
+    for path, data, attr, compress in files:
+        t = tree
+        *dirs, base = path.split("/")
+        for d in dirs:
+            t = t.setdefault(d, {})
+        t[base] = (data, attr, compress)
+
+    names = bytearray(name_entry(""))
+    ftab = bytearray(file_entry(0, 0x10, (0, 0, 0), 0, 0, -1))
+    dtab = bytearray()
+    body = bytearray()
+
+    def emit_dir(t, file_offset, parent_offset, upper_names):
+        dir_offset = len(dtab)
+        dtab.extend(bytes(dir_size))
+        list_offset = len(ftab)
+        items = sorted(t.items())
+        ftab.extend(bytes(file_size * len(items)))
+        for i, (name, v) in enumerate(items):
+            name_offset = len(names)
+            names.extend(name_entry(name))
+            e = list_offset + i * file_size
+            up = upper(name.encode("shift_jis"))
+            if isinstance(v, dict):
+                sub = emit_dir(v, e, dir_offset, up + upper_names)
+                ftab[e : e + file_size] = file_entry(name_offset, 0x10, dir_times, sub, 0, -1)
+                continue
+
+            data, attr, compress = v
+            stored = lz(data) if compress else data
+            file_key = key
+            if version == 8 and key:
+                file_key = key_create8(password + up + upper_names)
+            ftab[e : e + file_size] = file_entry(
+                name_offset, attr, times, len(body), len(data), len(stored) if compress else -1
+            )
+            # Key position starts at uncompressed size.
+            body.extend(xor(stored, file_key, len(data)))
+        dtab[dir_offset : dir_offset + dir_size] = dir_entry(file_offset, parent_offset, len(items), list_offset)
+        return dir_offset
+
+    emit_dir(tree, 0, -1, b"")
+    trailer = bytes(names + ftab + dtab)
+    ft, dt = len(names), len(names) + len(ftab)
+    if version == 5:
+        header = b"DX" + struct.pack("<HIIIIII", 5, len(trailer), 28, 28 + len(body), ft, dt, 932)
+    elif version == 8:
+        flags = 2 if key else 3  # NoHeadPress, NoKey
+        header = b"DX" + struct.pack("<HIQQQQII", 8, len(trailer), 64, 64 + len(body), ft, dt, 932, flags)
+        header += bytes(16)
+        return header + bytes(body) + xor(trailer, key)
+    else:
+        header = b"DX" + struct.pack("<HIQQQQQ", version, len(trailer), 48, 48 + len(body), ft, dt, 932)
+    return xor(header, key) + bytes(body) + xor(trailer, key)
+
+
+KEY = bytes(range(0x11, 0xDD, 0x11))
+READER_FILES = [
+    ("Game.ini", b"[Game]\r\n", 0x20, False),
+    ("BasicData/Game.dat", bytes(range(256)) * 8 + b"\xab\xab tail", 0x21, True),
+    ("BasicData/sub/empty.dat", b"", 0x22, False),
+    ("MapData/マップ.mps", b"map " * 100, 0x20, True),
+]
+for version in (5, 6, 7):
+    with open("v%d.dxa" % version, "wb") as f:
+        f.write(build(version, READER_FILES, KEY))
+with open("v8.dxa", "wb") as f:
+    f.write(build(8, READER_FILES, key_create8(b"secret"), b"secret"))
+
+# Writer sets all times to modification time and leaves them zero for directories.
+WRITER_FILES = [
+    ("Game.ini", b"[Game]\r\n", 0x20, False),
+    ("BasicData/Game.dat", b"game data", 0x21, False),
+    ("Picture/タイトル.png", b"\x89PNG", 0x22, False),
+]
+with open("writer6.dxa", "wb") as f:
+    f.write(build(6, WRITER_FILES, key_create6(b""), times=(FILETIME,) * 3, dir_times=(0, 0, 0)))
+with open("writer8.dxa", "wb") as f:
+    f.write(build(8, WRITER_FILES, key_create8(b"secret"), b"secret", times=(FILETIME,) * 3, dir_times=(0, 0, 0)))
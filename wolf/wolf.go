@@ -1,130 +1,600 @@
 package wolf
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"path"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
-	"golang.org/x/text/encoding/japanese"
+	"github.com/kaey/gamearc/internal/arc"
 	"golang.org/x/text/transform"
 )
 
 type Archive struct {
-	r     io.ReaderAt
-	size  int64
-	key   [12]byte
+	*arc.FS
+
+	r    io.ReaderAt
+	size int64
+	key  []byte
+
+	// Version is DXA format version.
+	Version int
+
+	// Codepage is Windows codepage of file names.
+	Codepage int
+
 	Files []File
 }
 
 type File struct {
 	r      io.ReaderAt
-	key    [12]byte
+	key    []byte
 	path   string
 	offset int64
 	size   int64
+
+	compressedSize int64
+	huffmanSize    int64
+
+	attr     int
+	created  time.Time
+	accessed time.Time
+	modified time.Time
 }
 
 func (f *File) Path() string {
 	return f.path
 }
 
+func (f *File) Offset() int64 {
+	return f.offset
+}
+
+func (f *File) Size() int64 {
+	return f.size
+}
+
+// Data reads the whole file into memory.
 func (f *File) Data() ([]byte, error) {
-	data := make([]byte, int(f.size))
-	_, err := f.r.ReadAt(data, f.offset)
-	if err != nil {
+	data := make([]byte, f.size)
+	if _, err := io.ReadFull(f.Reader(), data); err != nil {
 		return nil, err
 	}
-	for i := range data {
-		data[i] ^= f.key[(int(f.size)+i)%len(f.key)]
-	}
 
 	return data, nil
 }
 
+// Reader returns a reader which decrypts file data on the fly.
+// Compressed files are decompressed into memory on first read.
+func (f *File) Reader() *io.SectionReader {
+	if f.compressedSize > -1 || f.huffmanSize > -1 {
+		return io.NewSectionReader(&decodeReaderAt{f: f}, 0, f.size)
+	}
+
+	// Key position depends on decompressed size.
+	return io.NewSectionReader(&decryptReaderAt{r: f.r, key: f.key, startOffset: f.offset, keyOffset: int(f.size)}, f.offset, f.size)
+}
+
+// Open returns the same reader as Reader, or an error for files which can't be decoded.
+func (f *File) Open() (io.ReadSeeker, error) {
+	if f.huffmanSize > -1 {
+		return nil, errHuffman
+	}
+
+	return f.Reader(), nil
+}
+
+// errHuffman is returned for version 8 files compressed with huffman coding, which are not supported.
+var errHuffman = fmt.Errorf("huffman compressed file: %w", errors.ErrUnsupported)
+
+// decode reads and decompresses whole compressed file.
+func (f *File) decode() ([]byte, error) {
+	if f.huffmanSize > -1 {
+		return nil, errHuffman
+	}
+
+	data := make([]byte, int(f.compressedSize))
+	if _, err := f.r.ReadAt(data, f.offset); err != nil {
+		return nil, err
+	}
+	// Key position depends on decompressed size even for compressed files.
+	xor(data, f.key, int(f.size))
+
+	return decode(data)
+}
+
+type decryptReaderAt struct {
+	r           io.ReaderAt
+	key         []byte
+	startOffset int64
+	keyOffset   int
+}
+
+func (r *decryptReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	n, err = r.r.ReadAt(p, off)
+	xor(p[:n], r.key, r.keyOffset+int(off-r.startOffset))
+
+	return n, err
+}
+
+type decodeReaderAt struct {
+	f    *File
+	once sync.Once
+	data []byte
+	err  error
+}
+
+func (r *decodeReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	r.once.Do(func() {
+		r.data, r.err = r.f.decode()
+	})
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	return bytes.NewReader(r.data).ReadAt(p, off)
+}
+
+// ModTime returns last write time of the file, zero if it is not set.
+func (f *File) ModTime() time.Time {
+	return f.modified
+}
+
+// CreateTime returns creation time of the file, zero if it is not set.
+func (f *File) CreateTime() time.Time {
+	return f.created
+}
+
+// AccessTime returns last access time of the file, zero if it is not set.
+func (f *File) AccessTime() time.Time {
+	return f.accessed
+}
+
+func (f *File) ReadOnly() bool {
+	return f.attr&attrReadOnly != 0
+}
+
+func (f *File) Hidden() bool {
+	return f.attr&attrHidden != 0
+}
+
+// Meta returns compressed size of the file (-1 if it is not compressed), modification time and attributes.
+func (f *File) Meta() map[string]any {
+	return map[string]any{
+		"compressedSize": f.compressedSize,
+		"modified":       f.modified,
+		"readOnly":       f.ReadOnly(),
+		"hidden":         f.Hidden(),
+	}
+}
+
+// Options are optional parameters of OpenArchiveWithOptions.
+type Options struct {
+	// Key is a 12 bytes key of version 5, 6 or 7 archive.
+	// Version 8 keys can only be created from a password, opening such archive with Key fails.
+	Key []byte
+
+	// Password is a key string the archive key is created from, used by all versions.
+	// Version 8 archives fall back to known Passwords if it does not work.
+	Password string
+}
+
+// OpenArchive opens archive using key derived from the header or one of known Keys or Passwords.
 func OpenArchive(r io.ReaderAt, size int64) (*Archive, error) {
+	return OpenArchiveWithOptions(r, size, Options{})
+}
+
+// OpenArchiveWithOptions is like OpenArchive, but tries provided key first.
+func OpenArchiveWithOptions(r io.ReaderAt, size int64, opts Options) (*Archive, error) {
 	a := &Archive{r: r, size: size}
-	if err := a.readIndex(); err != nil {
+	if err := a.readIndex(&opts); err != nil {
 		return nil, fmt.Errorf("read index: %w", err)
 	}
+	a.FS = arc.NewFS(arc.Entries(a.Files))
 
 	return a, nil
 }
 
-func (a *Archive) readIndex() error {
-	header := make([]byte, 48)
-	if _, err := a.r.ReadAt(header[:], 0); err != nil {
+// header is decrypted archive header, its layout differs between versions.
+type header struct {
+	version         int
+	layout          layout
+	key             []byte
+	headSize        int
+	dataOffset      int
+	nameTableOffset int
+	fileTableOffset int
+	dirTableOffset  int
+	codepage        int
+	flags           int
+
+	// password is version 8 key string, file keys are derived from it.
+	password []byte
+}
+
+// layout describes tables which follow file data.
+type layout struct {
+	word     int // size of offsets and sizes
+	fileSize int // size of file entry
+	dirSize  int // size of directory entry
+}
+
+var (
+	layout5 = layout{word: 4, fileSize: 44, dirSize: 16}
+	layout6 = layout{word: 8, fileSize: 64, dirSize: 32}
+	layout8 = layout{word: 8, fileSize: 72, dirSize: 32}
+)
+
+var errNotDX = errors.New("file header must start with DX")
+
+// Version 8 header flags.
+const (
+	flagNoKey       = 1
+	flagNoHeadPress = 2
+)
+
+// File attributes, https://docs.microsoft.com/en-us/windows/win32/fileio/file-attribute-constants
+const (
+	attrReadOnly  = 0x1
+	attrHidden    = 0x2
+	attrDirectory = 0x10
+	attrArchive   = 0x20
+)
+
+func (a *Archive) readIndex(opts *Options) error {
+	raw := make([]byte, 64)
+	if n, err := a.r.ReadAt(raw, 0); err != nil && !(err == io.EOF && n >= 28) {
 		return err
 	}
 
-	copy(a.key[0:4], header[12:16])
-	copy(a.key[4:8], header[28:32])
-	copy(a.key[8:12], header[20:24])
+	h, err := parseHeader(raw, a.size, opts)
+	if err != nil {
+		return err
+	}
 
-	for i := range header {
-		header[i] ^= a.key[i%len(a.key)]
+	enc := Encoding(h.codepage)
+	if enc == nil {
+		return fmt.Errorf("unsupported codepage %v", h.codepage)
 	}
 
-	if header[0] != 'D' || header[1] != 'X' {
-		return fmt.Errorf("file header must start with DX, got %q", header[0:2])
+	trailer, err := readTables(a.r, a.size, h)
+	if err != nil && h.version >= 8 && h.key != nil {
+		for _, p := range Passwords {
+			if p.Password == opts.Password {
+				continue
+			}
+
+			h8 := *h
+			h8.password = []byte(p.Password)
+			h8.key = keyCreate8(h8.password)
+			if t, err8 := readTables(a.r, a.size, &h8); err8 == nil {
+				h, trailer, err = &h8, t, nil
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	a.Version = h.version
+	a.key = h.key
+	a.Codepage = h.codepage
+
+	d := &dirDecoder{
+		header:  h,
+		b:       trailer,
+		tr:      enc.NewDecoder(),
+		visited: make(map[int]bool),
+	}
+	if err := a.decodeDir(d, 0, "", nil); err != nil {
+		return fmt.Errorf("decode first dir: %w", err)
 	}
 
-	version := getUint16(header, 2)
-	if version != 6 {
-		return fmt.Errorf("expected version 6, got: %d", version)
+	return nil
+}
+
+// readTables reads name, file and directory tables and checks that they are decrypted with the right key.
+func readTables(r io.ReaderAt, size int64, h *header) ([]byte, error) {
+	trailer, err := readTrailer(r, size, h)
+	if err != nil {
+		return nil, err
 	}
 
-	trailerSize := getUint32(header, 4)
-	dataOffset := getUint64(header, 8)
-	trailerOffset := getUint64(header, 16)
-	fileTableOffset := getUint64(header, 24)
-	dirTableOffset := getUint64(header, 32)
-	codepage := getUint64(header, 40)
-	if codepage != 932 {
-		// https://docs.microsoft.com/en-us/windows/win32/intl/code-page-identifiers
-		return fmt.Errorf("unsupported codepage, expected 932(shift-jis), got: %v", codepage)
+	for _, t := range []struct {
+		name   string
+		offset int
+	}{
+		{"file", h.fileTableOffset},
+		{"directory", h.dirTableOffset},
+	} {
+		if t.offset < 0 || t.offset > len(trailer) {
+			return nil, fmt.Errorf("bad key: %s table offset %#x beyond header size %#x", t.name, t.offset, len(trailer))
+		}
 	}
 
-	trailer := make([]byte, trailerSize)
-	if _, err := a.r.ReadAt(trailer, int64(trailerOffset)); err != nil {
-		return err
+	d := &dirDecoder{header: h, b: trailer}
+	if err := d.check(d.dirTableOffset, h.layout.dirSize); err != nil {
+		return nil, fmt.Errorf("bad key: %w", err)
 	}
-	for i := range trailer {
-		trailer[i] ^= a.key[i%len(a.key)]
+
+	// Root directory has no parent, anything else means key is wrong.
+	if parent := d.entry(d.dirTableOffset, 1); parent != -1 {
+		return nil, fmt.Errorf("bad key: root directory has parent %#x", parent)
 	}
 
-	tr := japanese.ShiftJIS.NewDecoder()
-	if err := a.decodeDir(trailer, dataOffset, fileTableOffset, dirTableOffset, 0, tr, ""); err != nil {
-		return fmt.Errorf("decode first dir: %w", err)
+	return trailer, nil
+}
+
+// readTrailer reads and decrypts name, file and directory tables which follow file data.
+// DxLib compresses them in version 8 with LZ and then huffman, unless flagNoHeadPress is set.
+func readTrailer(r io.ReaderAt, size int64, h *header) ([]byte, error) {
+	if h.nameTableOffset < 0 || int64(h.nameTableOffset) > size {
+		return nil, fmt.Errorf("name table offset %#x beyond file size %#x", h.nameTableOffset, size)
+	}
+	rest := int(size) - h.nameTableOffset
+
+	if h.version < 8 || h.flags&flagNoHeadPress != 0 {
+		if h.headSize > rest {
+			return nil, fmt.Errorf("header size %#x beyond file size %#x", h.headSize, size)
+		}
+
+		trailer := make([]byte, h.headSize)
+		if _, err := r.ReadAt(trailer, int64(h.nameTableOffset)); err != nil {
+			return nil, err
+		}
+		xor(trailer, h.key, 0)
+
+		return trailer, nil
+	}
+
+	// Compressed header takes the rest of the file.
+	press := make([]byte, rest)
+	if _, err := r.ReadAt(press, int64(h.nameTableOffset)); err != nil {
+		return nil, err
+	}
+	xor(press, h.key, 0)
+
+	lz, err := huffmanDecode(press)
+	if err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	trailer, err := decode(lz)
+	if err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	if len(trailer) != h.headSize {
+		return nil, fmt.Errorf("header: expected %d bytes, got %d", h.headSize, len(trailer))
+	}
+
+	return trailer, nil
+}
+
+func parseHeader(raw []byte, size int64, opts *Options) (*header, error) {
+	// Version 8 header is not encrypted.
+	if raw[0] == 'D' && raw[1] == 'X' && getUint16(raw, 2) == 8 {
+		return parseHeader8(raw, opts)
+	}
+
+	var keys [][]byte
+	if opts.Key != nil {
+		keys = append(keys, opts.Key)
+	}
+	if opts.Password != "" {
+		keys = append(keys, keyCreate6([]byte(opts.Password)))
+	}
+	keys = append(keys, headerKey6(raw), headerKey5(raw, size))
+	for _, k := range Keys {
+		keys = append(keys, k.Key)
+	}
+
+	var firstErr error
+	for _, key := range keys {
+		if len(key) != 12 {
+			continue
+		}
+
+		h, err := decryptHeader(raw, key)
+		if err == nil {
+			return h, nil
+		}
+		if firstErr == nil && err != errNotDX {
+			firstErr = err
+		}
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return nil, errNotDX
+}
+
+// headerKey6 recovers key of versions 6 and 7 which have 64-bit offsets,
+// high bytes of those are always zero and contain key in plain.
+func headerKey6(raw []byte) []byte {
+	key := make([]byte, 12)
+	copy(key[0:4], raw[12:16])
+	copy(key[4:8], raw[28:32])
+	copy(key[8:12], raw[20:24])
+
+	return key
+}
+
+// headerKey5 recovers key from known parts of version 5 header:
+// magic and version, data offset which always follows 28 bytes header
+// and header size, since header tables are stored at the end of file.
+// Codepage is the only field left to tell if recovered key is correct.
+func headerKey5(raw []byte, size int64) []byte {
+	key := make([]byte, 12)
+	for i, b := range []byte{'D', 'X', 5, 0} {
+		key[i] = raw[i] ^ b
+	}
+
+	nameTableOffset := getUint32(raw, 12) ^ getUint32(key, 0)
+	if nameTableOffset < 28 || int64(nameTableOffset) >= size {
+		return nil
+	}
+	putUint32(key[4:8], getUint32(raw, 4)^(int(size)-nameTableOffset))
+	putUint32(key[8:12], getUint32(raw, 8)^28)
+
+	if Encoding(getUint32(raw, 24)^getUint32(key, 0)) == nil {
+		return nil
+	}
+
+	return key
+}
+
+// decryptHeader decrypts header of versions 5 to 7 with the key.
+func decryptHeader(raw, key []byte) (*header, error) {
+	dec := slices.Clone(raw[:48])
+	xor(dec, key, 0)
+	if dec[0] != 'D' || dec[1] != 'X' {
+		return nil, errNotDX
+	}
+
+	switch version := getUint16(dec, 2); version {
+	case 5:
+		return &header{
+			version:         version,
+			layout:          layout5,
+			key:             key,
+			headSize:        getUint32(dec, 4),
+			dataOffset:      getUint32(dec, 8),
+			nameTableOffset: getUint32(dec, 12),
+			fileTableOffset: getUint32(dec, 16),
+			dirTableOffset:  getUint32(dec, 20),
+			codepage:        getUint32(dec, 24),
+		}, nil
+	case 6, 7:
+		return &header{
+			version:         version,
+			layout:          layout6,
+			key:             key,
+			headSize:        getUint32(dec, 4),
+			dataOffset:      getUint64(dec, 8),
+			nameTableOffset: getUint64(dec, 16),
+			fileTableOffset: getUint64(dec, 24),
+			dirTableOffset:  getUint64(dec, 32),
+			codepage:        getUint64(dec, 40),
+		}, nil
+	default:
+		return nil, fmt.Errorf("expected version 5, 6, 7 or 8, got: %d", version)
+	}
+}
+
+func parseHeader8(raw []byte, opts *Options) (*header, error) {
+	if opts.Key != nil {
+		return nil, errors.New("version 8 key can only be created from a password")
+	}
+
+	h := &header{
+		version:         8,
+		layout:          layout8,
+		headSize:        getUint32(raw, 4),
+		dataOffset:      getUint64(raw, 8),
+		nameTableOffset: getUint64(raw, 16),
+		fileTableOffset: getUint64(raw, 24),
+		dirTableOffset:  getUint64(raw, 32),
+		codepage:        getUint32(raw, 40),
+		flags:           getUint32(raw, 44),
+	}
+	if h.flags&flagNoKey == 0 {
+		h.password = []byte(opts.Password)
+		h.key = keyCreate8(h.password)
+	}
+
+	return h, nil
+}
+
+type dirDecoder struct {
+	*header
+	b  []byte
+	tr transform.Transformer
+
+	// visited holds offsets of decoded directories, so that cycles are not followed.
+	visited map[int]bool
+}
+
+// check returns an error if n bytes at offset are beyond decoded header.
+func (d *dirDecoder) check(offset, n int) error {
+	if offset < 0 || offset > len(d.b)-n {
+		return fmt.Errorf("entry at %#x of size %#x beyond header size %#x", offset, n, len(d.b))
 	}
 
 	return nil
 }
 
-func (a *Archive) decodeDir(b []byte, dataOffset, fileTableOffset, dirTableOffset, curDirOffset int, tr transform.Transformer, curpath string) error {
-	//fileOffset := getUint64(b, dirTableOffset+curDirOffset+0)
-	//parentOffset := getUint64(b, dirTableOffset+curDirOffset+8)
-	nFiles := getUint64(b, dirTableOffset+curDirOffset+16)
-	filelistOffset := getUint64(b, dirTableOffset+curDirOffset+24)
+// entry reads word-sized field i of the entry at offset, -1 is returned for fields with all bits set.
+// Caller must check the entry bounds.
+func (d *dirDecoder) entry(offset, i int) int {
+	if d.layout.word == 4 {
+		v := getUint32(d.b, offset+i*4)
+		if v == 0xffffffff {
+			return -1
+		}
+		return v
+	}
+
+	return getUint64(d.b, offset+i*8)
+}
+
+// decodeDir walks directory at dirOffset of directory table.
+// Names holds uppercase names of current directory and its parents, they are part of version 8 file keys.
+func (a *Archive) decodeDir(d *dirDecoder, dirOffset int, curpath string, names []byte) error {
+	if d.visited[dirOffset] {
+		return fmt.Errorf("directory at %#x is referenced twice", dirOffset)
+	}
+	d.visited[dirOffset] = true
+
+	l := d.layout
+	if dirOffset < 0 || dirOffset > len(d.b) {
+		return fmt.Errorf("directory offset %#x beyond header size %#x", dirOffset, len(d.b))
+	}
+	dir := d.dirTableOffset + dirOffset
+	if err := d.check(dir, l.dirSize); err != nil {
+		return fmt.Errorf("directory: %w", err)
+	}
+	//fileOffset := d.entry(dir, 0)
+	//parentOffset := d.entry(dir, 1)
+	nFiles := d.entry(dir, 2)
+	filelistOffset := d.entry(dir, 3)
+	if filelistOffset < 0 || filelistOffset > len(d.b) {
+		return fmt.Errorf("file list offset %#x beyond header size %#x", filelistOffset, len(d.b))
+	}
 
 	for i := 0; i < nFiles; i++ {
-		nameOffset := getUint64(b, fileTableOffset+filelistOffset+i*64+0)
-		attr := getUint64(b, fileTableOffset+filelistOffset+i*64+8)
-		//createTime := getUint64(b, fileTableOffset+filelistOffset+i*64+16)
-		//accessTime := getUint64(b, fileTableOffset+filelistOffset+i*64+24)
-		//writeTime := getUint64(b, fileTableOffset+filelistOffset+i*64+32)
-		filedataOffset := getUint64(b, fileTableOffset+filelistOffset+i*64+40)
-		size := getUint64(b, fileTableOffset+filelistOffset+i*64+48)
-		compressedDataSize := getUint64(b, fileTableOffset+filelistOffset+i*64+56)
-
-		if compressedDataSize > -1 {
-			continue
-			//return fmt.Errorf("compressed archives not supported")
+		e := d.fileTableOffset + filelistOffset + i*l.fileSize
+		if err := d.check(e, l.fileSize); err != nil {
+			return fmt.Errorf("file: %w", err)
+		}
+		nameOffset := d.entry(e, 0)
+		attr := d.entry(e, 1)
+		// Three 64-bit timestamps follow attributes: create, access and write time.
+		times := e + 2*l.word
+		fields := times + 24
+		filedataOffset := d.entry(fields, 0)
+		size := d.entry(fields, 1)
+		compressedDataSize := d.entry(fields, 2)
+		huffmanDataSize := -1
+		if d.version >= 8 {
+			huffmanDataSize = d.entry(fields, 3)
 		}
 
-		nameLength := getUint16(b, nameOffset) * 4
-		//nameParity := getUint16(b, nameOffset+2)
-		name, _, err := transform.String(tr, strings.Trim(string(b[nameOffset+4+nameLength:nameOffset+4+nameLength*2]), "\x00"))
+		if err := d.check(nameOffset, 4); err != nil {
+			return fmt.Errorf("name: %w", err)
+		}
+		nameLength := getUint16(d.b, nameOffset) * 4
+		if err := d.check(nameOffset, 4+nameLength*2); err != nil {
+			return fmt.Errorf("name: %w", err)
+		}
+		//nameParity := getUint16(d.b, nameOffset+2)
+		upper := bytes.TrimRight(d.b[nameOffset+4:nameOffset+4+nameLength], "\x00")
+		name, _, err := transform.String(d.tr, strings.Trim(string(d.b[nameOffset+4+nameLength:nameOffset+4+nameLength*2]), "\x00"))
 		if err != nil {
 			return err
 		}
@@ -133,26 +603,83 @@ func (a *Archive) decodeDir(b []byte, dataOffset, fileTableOffset, dirTableOffse
 			return fmt.Errorf("bad path: %q", name)
 		}
 
-		if attr&0x10 > 0 { // is a directory, https://docs.microsoft.com/en-us/windows/win32/fileio/file-attribute-constants
-			if err := a.decodeDir(b, dataOffset, fileTableOffset, dirTableOffset, filedataOffset, tr, path.Join(curpath, name)); err != nil {
+		if attr&attrDirectory > 0 {
+			if err := a.decodeDir(d, filedataOffset, path.Join(curpath, name), append(slices.Clone(upper), names...)); err != nil {
 				return err
 			}
 
 			continue
 		}
 
+		key := a.key
+		if d.version >= 8 && key != nil {
+			key = keyCreate8(slices.Concat(d.password, upper, names))
+		}
+
 		a.Files = append(a.Files, File{
 			r:      a.r,
-			key:    a.key,
+			key:    key,
 			path:   path.Join(curpath, name),
-			offset: int64(dataOffset + filedataOffset),
+			offset: int64(d.dataOffset + filedataOffset),
 			size:   int64(size),
+
+			compressedSize: int64(compressedDataSize),
+			huffmanSize:    int64(huffmanDataSize),
+
+			attr:     attr,
+			created:  filetime(getUint64(d.b, times)),
+			accessed: filetime(getUint64(d.b, times+8)),
+			modified: filetime(getUint64(d.b, times+16)),
 		})
 	}
 
 	return nil
 }
 
+// filetimeEpoch is the number of 100ns intervals between 1601, FILETIME epoch, and 1970.
+const filetimeEpoch = 116444736000000000
+
+// filetime converts windows FILETIME, 100ns intervals since 1601, to time.
+func filetime(v int) time.Time {
+	if v == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, (int64(v)-filetimeEpoch)*100).UTC()
+}
+
+// toFiletime converts time to windows FILETIME, zero time becomes zero.
+func toFiletime(t time.Time) int {
+	if t.IsZero() {
+		return 0
+	}
+
+	return int(t.UnixNano()/100 + filetimeEpoch)
+}
+
+// xor applies key to b as if b was at position pos of encrypted stream.
+func xor(b, key []byte, pos int) {
+	if len(key) == 0 {
+		return
+	}
+
+	for i := range b {
+		b[i] ^= key[(pos+i)%len(key)]
+	}
+}
+
+func putUint32(b []byte, v int) {
+	_ = b[3]
+	b[0], b[1], b[2], b[3] = byte(v), byte(v>>8), byte(v>>16), byte(v>>24)
+}
+
+func putUint64(b []byte, v int) {
+	_ = b[7]
+	for i := range 8 {
+		b[i] = byte(v >> (8 * i))
+	}
+}
+
 func getUint16(b []byte, offset int) int {
 	b = b[offset:]
 	_ = b[1]
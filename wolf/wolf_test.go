@@ -0,0 +1,177 @@
+package wolf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestOpen reads archives of every version, see testdata/gen.py.
+// Files are listed in the order of directory walk.
+func TestOpen(t *testing.T) {
+	modified := time.Date(2019, 4, 17, 18, 40, 0, 200, time.UTC)
+	files := []struct {
+		path     string
+		data     string
+		readOnly bool
+		hidden   bool
+		compress bool
+	}{
+		{path: "BasicData/Game.dat", data: strings.Repeat(string(literalBytes()), 8) + "\xab\xab tail", readOnly: true, compress: true},
+		{path: "BasicData/sub/empty.dat", hidden: true},
+		{path: "Game.ini", data: "[Game]\r\n"},
+		{path: "MapData/マップ.mps", data: strings.Repeat("map ", 100), compress: true},
+	}
+
+	for _, tc := range []struct {
+		version int
+		opts    Options
+	}{
+		{5, Options{}},
+		{5, Options{Key: []byte("\x11\x22\x33\x44\x55\x66\x77\x88\x99\xaa\xbb\xcc")}},
+		{6, Options{}},
+		{7, Options{}},
+		{8, Options{Password: "secret"}},
+	} {
+		t.Run(fmt.Sprintf("v%d/%x", tc.version, tc.opts.Key), func(t *testing.T) {
+			data, err := os.ReadFile(fmt.Sprintf("testdata/v%d.dxa", tc.version))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a, err := OpenArchiveWithOptions(bytes.NewReader(data), int64(len(data)), tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Version != tc.version || a.Codepage != 932 {
+				t.Errorf("got version %d, codepage %d", a.Version, a.Codepage)
+			}
+			if len(a.Files) != len(files) {
+				t.Fatalf("got %d files, want %d", len(a.Files), len(files))
+			}
+
+			for i, want := range files {
+				f := &a.Files[i]
+				if f.Path() != want.path {
+					t.Errorf("file %d: got %q, want %q", i, f.Path(), want.path)
+					continue
+				}
+				got, err := f.Data()
+				if err != nil {
+					t.Fatalf("%s: %v", want.path, err)
+				}
+				if string(got) != want.data {
+					t.Errorf("%s: got %q, want %q", want.path, got, want.data)
+				}
+				if compressed := f.compressedSize > -1; compressed != want.compress {
+					t.Errorf("%s: got compressed %v", want.path, compressed)
+				}
+				if f.ReadOnly() != want.readOnly || f.Hidden() != want.hidden {
+					t.Errorf("%s: got read-only %v, hidden %v", want.path, f.ReadOnly(), f.Hidden())
+				}
+				if !f.ModTime().Equal(modified) {
+					t.Errorf("%s: got modified %v, want %v", want.path, f.ModTime(), modified)
+				}
+			}
+		})
+	}
+}
+
+// literalBytes returns all byte values in order.
+func literalBytes() []byte {
+	b := make([]byte, 256)
+	for i := range b {
+		b[i] = byte(i)
+	}
+
+	return b
+}
+
+// TestCorruptIndex checks that offsets in the header tables are validated.
+func TestCorruptIndex(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetVersion(8)
+	for _, name := range []string{"a.txt", "Data/b.txt"} {
+		err := w.AddFile(FileHeader{Name: name, Size: int64(len(name))}, func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(name)), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Root directory lists Data first, its entry follows the root file entry.
+	nameTableOffset := getUint64(buf.Bytes(), 16)
+	data := nameTableOffset + getUint64(buf.Bytes(), 24) + layout8.fileSize
+	for _, tc := range []struct {
+		name   string
+		offset int
+		size   int
+		value  int
+	}{
+		{"header size", 4, 4, 1 << 20},
+		{"name table offset", 16, 8, 1 << 40},
+		{"file table offset", 24, 8, 1 << 20},
+		{"directory table offset", 32, 8, -1},
+		{"name offset", data, 8, 1 << 20},
+		{"directory offset", data + 40, 8, 1 << 20},
+		{"directory cycle", data + 40, 8, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := bytes.Clone(buf.Bytes())
+			key := keyCreate8(nil)
+			if tc.offset >= nameTableOffset {
+				xor(b[nameTableOffset:], key, 0)
+			}
+			if tc.size == 4 {
+				putUint32(b[tc.offset:], tc.value)
+			} else {
+				putUint64(b[tc.offset:], tc.value)
+			}
+			if tc.offset >= nameTableOffset {
+				xor(b[nameTableOffset:], key, 0)
+			}
+
+			if _, err := OpenArchive(bytes.NewReader(b), int64(len(b))); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestOpenV8Options(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetVersion(8)
+	err := w.AddFile(FileHeader{Name: "a.txt", Size: 1}, func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("a")), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := bytes.NewReader(buf.Bytes())
+
+	if _, err := OpenArchiveWithOptions(r, r.Size(), Options{Key: []byte("0123456789ab")}); err == nil {
+		t.Error("key: expected error")
+	}
+
+	// Archive is encrypted with DxLib default password, which is one of known Passwords.
+	a, err := OpenArchiveWithOptions(r, r.Size(), Options{Password: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := a.ReadFile("a.txt"); err != nil || string(data) != "a" {
+		t.Errorf("got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,425 @@
+package wolf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+	"time"
+)
+
+// writerCodepage is the codepage of names in written archives, Shift-JIS as Wolf RPG expects.
+const writerCodepage = 932
+
+// FileHeader describes a file added to Writer.
+type FileHeader struct {
+	Name     string
+	Size     int64
+	Modified time.Time
+	ReadOnly bool
+	Hidden   bool
+}
+
+// Writer creates version 6 or 8 archives.
+// Files are collected with Add, AddFile or AddFS and written out by Close,
+// because header contains offset of the index which follows file data.
+type Writer struct {
+	w        io.Writer
+	version  int
+	opts     Options
+	compress bool
+	files    []writerFile
+}
+
+type writerFile struct {
+	FileHeader
+	open func() (io.ReadCloser, error)
+}
+
+// NewWriter returns a Writer of version 6 archives with DxLib default key.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, version: 6}
+}
+
+// SetVersion sets archive version, either 6 or 8.
+func (w *Writer) SetVersion(version int) error {
+	if version != 6 && version != 8 {
+		return fmt.Errorf("expected version 6 or 8, got: %d", version)
+	}
+	w.version = version
+
+	return nil
+}
+
+// SetOptions sets the archive key. Version 8 keys can only be created from a password.
+func (w *Writer) SetOptions(opts Options) {
+	w.opts = opts
+}
+
+// SetCompression enables LZ compression of files which become smaller with it.
+// Compressed data is kept in memory until Close returns.
+func (w *Writer) SetCompression(v bool) {
+	w.compress = v
+}
+
+// Add adds a regular file. Open is called when the archive is written and must return exactly size bytes.
+func (w *Writer) Add(name string, size int64, open func() (io.ReadCloser, error)) error {
+	return w.AddFile(FileHeader{Name: name, Size: size}, open)
+}
+
+// AddFile is like Add, but allows to set modification time and attributes.
+func (w *Writer) AddFile(fh FileHeader, open func() (io.ReadCloser, error)) error {
+	if !fs.ValidPath(fh.Name) || fh.Name == "." {
+		return fmt.Errorf("bad path: %q", fh.Name)
+	}
+	if fh.Size < 0 {
+		return fmt.Errorf("%s: bad size %d", fh.Name, fh.Size)
+	}
+
+	w.files = append(w.files, writerFile{FileHeader: fh, open: open})
+	return nil
+}
+
+// AddFS adds all regular files from fsys with their modification times,
+// files without write permission are marked read-only.
+func (w *Writer) AddFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		fh := FileHeader{Name: name, Size: info.Size(), Modified: info.ModTime(), ReadOnly: info.Mode()&0o222 == 0}
+		return w.AddFile(fh, func() (io.ReadCloser, error) {
+			return fsys.Open(name)
+		})
+	})
+}
+
+// node is a file or a directory of archive tree.
+type node struct {
+	name     string
+	file     *writerFile
+	children []*node
+}
+
+func (n *node) add(f *writerFile) error {
+	elems := strings.Split(f.Name, "/")
+	for i, e := range elems {
+		idx := slices.IndexFunc(n.children, func(c *node) bool { return c.name == e })
+		if idx < 0 {
+			n.children = append(n.children, &node{name: e})
+			idx = len(n.children) - 1
+		}
+		n = n.children[idx]
+
+		if n.file != nil {
+			if i == len(elems)-1 {
+				return fmt.Errorf("%s: duplicate path", f.Name)
+			}
+			return fmt.Errorf("%s: parent is a file", f.Name)
+		}
+	}
+	if len(n.children) > 0 {
+		return fmt.Errorf("%s: path is a directory", f.Name)
+	}
+	n.file = f
+
+	return nil
+}
+
+// tableWriter builds name, file and directory tables which form the archive index.
+type tableWriter struct {
+	*Writer
+	layout   layout
+	password []byte
+
+	names, files, dirs []byte
+
+	// data lists files in order of their data, dataSize is the total size of it.
+	data     []dataFile
+	dataSize int
+}
+
+type dataFile struct {
+	*writerFile
+	key        []byte
+	compressed []byte
+}
+
+// Close writes the archive. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	root := &node{}
+	for i := range w.files {
+		if err := root.add(&w.files[i]); err != nil {
+			return err
+		}
+	}
+
+	t := &tableWriter{Writer: w, layout: layout6}
+	headerSize := 48
+	key := w.opts.Key
+	if key == nil {
+		key = keyCreate6([]byte(w.opts.Password))
+	}
+	if w.version == 8 {
+		if w.opts.Key != nil {
+			return errors.New("version 8 key can only be created from a password")
+		}
+		t.layout = layout8
+		t.password = []byte(w.opts.Password)
+		key = keyCreate8(t.password)
+		headerSize = 64
+	}
+
+	// Root directory has an empty name and a file entry, which is not listed in any directory.
+	if _, _, err := t.addName(""); err != nil {
+		return err
+	}
+	t.files = make([]byte, t.layout.fileSize)
+	t.putFileEntry(0, 0, attrDirectory, time.Time{}, 0, 0, -1)
+	if _, err := t.addDir(root, 0, -1, nil, key); err != nil {
+		return err
+	}
+
+	trailer := slices.Concat(t.names, t.files, t.dirs)
+	xor(trailer, key, 0)
+
+	header := make([]byte, headerSize)
+	header[0], header[1] = 'D', 'X'
+	header[2] = byte(w.version)
+	putUint32(header[4:8], len(trailer))
+	putUint64(header[8:16], headerSize)
+	putUint64(header[16:24], headerSize+t.dataSize)
+	putUint64(header[24:32], len(t.names))
+	putUint64(header[32:40], len(t.names)+len(t.files))
+	if w.version == 8 {
+		putUint32(header[40:44], writerCodepage)
+		putUint32(header[44:48], flagNoHeadPress)
+	} else {
+		putUint64(header[40:48], writerCodepage)
+		xor(header, key, 0)
+	}
+
+	bw := bufio.NewWriter(w.w)
+	bw.Write(header)
+	for _, f := range t.data {
+		if err := writeFile(bw, f); err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+	bw.Write(trailer)
+
+	return bw.Flush()
+}
+
+// addDir adds directory n and its children to the tables, returning offset of its directory entry.
+// Names holds uppercase names of n and its parents, they are part of version 8 file keys.
+func (t *tableWriter) addDir(n *node, fileOffset, parentOffset int, names, key []byte) (int, error) {
+	l := t.layout
+	slices.SortFunc(n.children, func(a, b *node) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	dirOffset := len(t.dirs)
+	t.dirs = append(t.dirs, make([]byte, l.dirSize)...)
+	listOffset := len(t.files)
+	t.files = append(t.files, make([]byte, len(n.children)*l.fileSize)...)
+
+	for i, c := range n.children {
+		e := listOffset + i*l.fileSize
+		nameOffset, upper, err := t.addName(c.name)
+		if err != nil {
+			return 0, err
+		}
+
+		if c.file == nil {
+			sub, err := t.addDir(c, e, dirOffset, slices.Concat(upper, names), key)
+			if err != nil {
+				return 0, err
+			}
+			t.putFileEntry(e, nameOffset, attrDirectory, time.Time{}, sub, 0, -1)
+			continue
+		}
+
+		f := dataFile{writerFile: c.file, key: key}
+		if t.version == 8 {
+			f.key = keyCreate8(slices.Concat(t.password, upper, names))
+		}
+		if t.compress {
+			if f.compressed, err = compressFile(c.file); err != nil {
+				return 0, fmt.Errorf("%s: %w", c.file.Name, err)
+			}
+		}
+
+		attr := attrArchive
+		if c.file.ReadOnly {
+			attr |= attrReadOnly
+		}
+		if c.file.Hidden {
+			attr |= attrHidden
+		}
+		compressedSize := -1
+		stored := int(c.file.Size)
+		if f.compressed != nil {
+			compressedSize = len(f.compressed)
+			stored = compressedSize
+		}
+		t.putFileEntry(e, nameOffset, attr, c.file.Modified, t.dataSize, int(c.file.Size), compressedSize)
+
+		t.data = append(t.data, f)
+		t.dataSize += stored
+	}
+
+	w := l.word
+	putUint64(t.dirs[dirOffset:], fileOffset)
+	putUint64(t.dirs[dirOffset+w:], parentOffset)
+	putUint64(t.dirs[dirOffset+2*w:], len(n.children))
+	putUint64(t.dirs[dirOffset+3*w:], listOffset)
+
+	return dirOffset, nil
+}
+
+// addName adds name to the name table, returning its offset and uppercase name.
+//
+// Entry consists of uint16 length in 4 bytes units, uint16 parity (sum of uppercase name bytes),
+// uppercase name and the name itself, both are zero-terminated and padded to the length.
+func (t *tableWriter) addName(name string) (int, []byte, error) {
+	b, err := Encoding(writerCodepage).NewEncoder().Bytes([]byte(name))
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	n := (len(b) + 4) / 4 * 4
+	upper := upperShiftJIS(b)
+	parity := 0
+	for _, c := range upper {
+		parity += int(c)
+	}
+
+	offset := len(t.names)
+	entry := make([]byte, 4+2*n)
+	entry[0], entry[1] = byte(n/4), byte(n/4>>8)
+	entry[2], entry[3] = byte(parity), byte(parity>>8)
+	copy(entry[4:], upper)
+	copy(entry[4+n:], b)
+	t.names = append(t.names, entry...)
+
+	return offset, upper, nil
+}
+
+func (t *tableWriter) putFileEntry(e, nameOffset, attr int, modified time.Time, dataOffset, size, compressedSize int) {
+	w := t.layout.word
+	ft := toFiletime(modified)
+	putUint64(t.files[e:], nameOffset)
+	putUint64(t.files[e+w:], attr)
+	// Create, access and write times are all set to modification time.
+	putUint64(t.files[e+2*w:], ft)
+	putUint64(t.files[e+2*w+8:], ft)
+	putUint64(t.files[e+2*w+16:], ft)
+	fields := e + 2*w + 24
+	putUint64(t.files[fields:], dataOffset)
+	putUint64(t.files[fields+w:], size)
+	putUint64(t.files[fields+2*w:], compressedSize)
+	if t.version == 8 {
+		putUint64(t.files[fields+3*w:], -1) // Huffman compressed size.
+	}
+}
+
+// upperShiftJIS uppercases ascii letters of Shift-JIS string, skipping trail bytes of double-byte characters.
+func upperShiftJIS(b []byte) []byte {
+	upper := slices.Clone(b)
+	for i := 0; i < len(upper); i++ {
+		c := upper[i]
+		switch {
+		case c >= 0x81 && c <= 0x9f || c >= 0xe0 && c <= 0xfc:
+			i++
+		case c >= 'a' && c <= 'z':
+			upper[i] = c - 'a' + 'A'
+		}
+	}
+
+	return upper
+}
+
+// compressFile returns compressed file data, nil if compression does not make it smaller.
+func compressFile(f *writerFile) ([]byte, error) {
+	data, err := readFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	compressed := encode(data)
+	if len(compressed) >= len(data) {
+		return nil, nil
+	}
+
+	return compressed, nil
+}
+
+func readFile(f *writerFile) ([]byte, error) {
+	r, err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data := make([]byte, f.Size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("expected %d bytes: %w", f.Size, err)
+	}
+
+	return data, nil
+}
+
+// writeFile writes encrypted file data, key position starts at uncompressed size as in File.Reader.
+func writeFile(w io.Writer, f dataFile) error {
+	if f.compressed != nil {
+		xor(f.compressed, f.key, int(f.Size))
+		_, err := w.Write(f.compressed)
+		return err
+	}
+
+	r, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	ew := &encryptWriter{w: w, key: f.key, pos: int(f.Size)}
+	n, err := io.Copy(ew, io.LimitReader(r, f.Size))
+	if err != nil {
+		return err
+	}
+	if n != f.Size {
+		return fmt.Errorf("expected %d bytes, got %d", f.Size, n)
+	}
+
+	return nil
+}
+
+type encryptWriter struct {
+	w   io.Writer
+	key []byte
+	pos int
+	buf []byte
+}
+
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf[:0], p...)
+	xor(w.buf, w.key, w.pos)
+	n, err := w.w.Write(w.buf)
+	w.pos += n
+
+	return n, err
+}
@@ -0,0 +1,163 @@
+package wolf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWriterOutput compares written archives with ones built independently by testdata/gen.py.
+func TestWriterOutput(t *testing.T) {
+	modified := time.Date(2019, 4, 17, 18, 40, 0, 0, time.UTC)
+	files := []struct {
+		FileHeader
+		data string
+	}{
+		{FileHeader{Name: "Game.ini", Modified: modified}, "[Game]\r\n"},
+		{FileHeader{Name: "BasicData/Game.dat", Modified: modified, ReadOnly: true}, "game data"},
+		{FileHeader{Name: "Picture/タイトル.png", Modified: modified, Hidden: true}, "\x89PNG"},
+	}
+
+	for _, tc := range []struct {
+		version int
+		opts    Options
+	}{
+		{6, Options{}},
+		{8, Options{Password: "secret"}},
+	} {
+		t.Run(fmt.Sprint(tc.version), func(t *testing.T) {
+			want, err := os.ReadFile(fmt.Sprintf("testdata/writer%d.dxa", tc.version))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+			if err := w.SetVersion(tc.version); err != nil {
+				t.Fatal(err)
+			}
+			w.SetOptions(tc.opts)
+			for _, f := range files {
+				fh := f.FileHeader
+				fh.Size = int64(len(f.data))
+				err := w.AddFile(fh, func() (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader(f.data)), nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := buf.Bytes(); !bytes.Equal(got, want) {
+				for i := range min(len(got), len(want)) {
+					if got[i] != want[i] {
+						t.Fatalf("output differs at %#x: got %d bytes, want %d", i, len(got), len(want))
+					}
+				}
+				t.Fatalf("got %d bytes, want %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 600, time.UTC)
+	files := []struct {
+		FileHeader
+		data string
+	}{
+		{FileHeader{Name: "Game.ini"}, "[Game]\r\n"},
+		{FileHeader{Name: "BasicData/Game.dat", Modified: modified, ReadOnly: true}, strings.Repeat("game data ", 200)},
+		{FileHeader{Name: "MapData/a/b/Map001.mps", Hidden: true}, "map"},
+		{FileHeader{Name: "Picture/empty.png"}, ""},
+		{FileHeader{Name: "Picture/タイトル.png"}, "\x89PNG"},
+	}
+
+	for _, tc := range []struct {
+		version  int
+		compress bool
+		opts     Options
+	}{
+		{6, false, Options{}},
+		{6, true, Options{Password: "secret"}},
+		{6, false, Options{Key: []byte("0123456789ab")}},
+		{8, false, Options{}},
+		{8, true, Options{Password: "secret"}},
+	} {
+		t.Run(fmt.Sprintf("v%d/compress=%v/%q", tc.version, tc.compress, tc.opts.Password), func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+			if err := w.SetVersion(tc.version); err != nil {
+				t.Fatal(err)
+			}
+			w.SetOptions(tc.opts)
+			w.SetCompression(tc.compress)
+			for _, f := range files {
+				fh := f.FileHeader
+				fh.Size = int64(len(f.data))
+				err := w.AddFile(fh, func() (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader(f.data)), nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			a, err := OpenArchiveWithOptions(bytes.NewReader(buf.Bytes()), int64(buf.Len()), tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Version != tc.version {
+				t.Errorf("got version %d, want %d", a.Version, tc.version)
+			}
+			if len(a.Files) != len(files) {
+				t.Fatalf("got %d files, want %d", len(a.Files), len(files))
+			}
+			for _, f := range files {
+				got, err := a.Stat(f.Name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, err := a.ReadFile(f.Name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != f.data {
+					t.Errorf("%s: got %q, want %q", f.Name, data, f.data)
+				}
+				if !got.ModTime().Equal(f.Modified) {
+					t.Errorf("%s: got modified %v, want %v", f.Name, got.ModTime(), f.Modified)
+				}
+			}
+			for _, f := range a.Files {
+				for _, want := range files {
+					if f.Path() != want.Name {
+						continue
+					}
+					if f.ReadOnly() != want.ReadOnly || f.Hidden() != want.Hidden {
+						t.Errorf("%s: got read-only %v, hidden %v", f.Path(), f.ReadOnly(), f.Hidden())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWriterV8Key(t *testing.T) {
+	w := NewWriter(io.Discard)
+	w.SetVersion(8)
+	w.SetOptions(Options{Key: []byte("0123456789ab")})
+	if err := w.Close(); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,130 @@
+package zip
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/kaey/gamearc/internal/arc"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+type Archive struct {
+	*arc.FS
+
+	r     io.ReaderAt
+	size  int64
+	Files []File
+
+	// Dirs are paths of directory entries, which are not listed in Files.
+	Dirs []string
+}
+
+type File struct {
+	f    *zip.File
+	path string
+}
+
+func (f *File) Path() string {
+	return f.path
+}
+
+func (f *File) Offset() int64 {
+	offset, err := f.f.DataOffset()
+	if err != nil {
+		return -1
+	}
+
+	return offset
+}
+
+func (f *File) Size() int64 {
+	return int64(f.f.UncompressedSize64)
+}
+
+// Open returns a reader over uncompressed file data.
+// Stored files are read directly from the archive, others are decompressed into memory.
+func (f *File) Open() (io.ReadSeeker, error) {
+	if f.f.Method == zip.Store {
+		if r, err := f.f.OpenRaw(); err == nil {
+			if sr, ok := r.(*io.SectionReader); ok {
+				return sr, nil
+			}
+		}
+	}
+
+	r, err := f.f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(data), nil
+}
+
+func (f *File) Meta() map[string]any {
+	return map[string]any{
+		"method":         f.f.Method,
+		"compressedSize": f.f.CompressedSize64,
+		"modified":       f.f.Modified,
+	}
+}
+
+func OpenArchive(r io.ReaderAt, size int64) (*Archive, error) {
+	a := &Archive{r: r, size: size}
+	if err := a.readIndex(); err != nil {
+		return nil, fmt.Errorf("read index: %w", err)
+	}
+	a.FS = arc.NewFS(arc.Entries(a.Files))
+
+	return a, nil
+}
+
+func (a *Archive) readIndex() error {
+	zr, err := zip.NewReader(a.r, a.size)
+	if err != nil {
+		return err
+	}
+
+	// Non-utf8 names are decoded as shift-jis, since most games packed with zip are japanese.
+	tr := japanese.ShiftJIS.NewDecoder()
+	for _, f := range zr.File {
+		p := f.Name
+		if f.NonUTF8 {
+			p, _, err = transform.String(tr, p)
+			if err != nil {
+				return err
+			}
+		}
+
+		p = path.Clean(strings.ReplaceAll(p, "\\", "/"))
+		if path.IsAbs(p) {
+			return fmt.Errorf("archive contains a file with absolute path: %q", p)
+		}
+		if strings.Split(p, "/")[0] == ".." {
+			return fmt.Errorf("archive contains a file with path that leads outside of its root: %q", p)
+		}
+		if f.FileInfo().IsDir() {
+			if p != "." {
+				a.Dirs = append(a.Dirs, p)
+			}
+			continue
+		}
+
+		a.Files = append(a.Files, File{
+			f:    f,
+			path: p,
+		})
+	}
+
+	return nil
+}